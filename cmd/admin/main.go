@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"rest-api-go-gin/internal/backup"
+	"rest-api-go-gin/internal/cli"
+	"rest-api-go-gin/internal/config"
+	"rest-api-go-gin/internal/database"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+const usage = `Usage: admin [flags] <command> [argument]
+
+Commands:
+  backup        back up the SQLite database while the API keeps running
+  backups       list the backups in the backup directory
+  restore FILE  replace the SQLite database with a backup; stop the API first
+  check         run PRAGMA integrity_check and foreign_key_check
+  grant EMAIL   make a user an administrator
+  revoke EMAIL  take administrator access away from a user
+
+Flags:
+`
+
+type options struct {
+	databaseURL string
+	backups     backup.Options
+	database    config.Database
+	yes         bool
+}
+
+func main() {
+	log.SetFlags(0)
+
+	// The defaults come from the configuration of the API, so that the tool
+	// works on the same database and backups.
+	cfg, err := config.LoadEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := options{database: cfg.Database}
+
+	flag.StringVar(&opts.databaseURL, "database", cfg.Database.URL,
+		"database URL, or the path of a SQLite database file")
+	flag.StringVar(&opts.backups.Dir, "dir", cfg.Backup.Dir, "backup directory")
+	flag.BoolVar(&opts.backups.Gzip, "gzip", cfg.Backup.Gzip, "compress backups with gzip")
+	flag.IntVar(&opts.backups.Keep, "keep", cfg.Backup.Keep, "number of backups to keep, 0 for all")
+	flag.BoolVar(&opts.yes, "yes", false, "restore without asking for confirmation")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(context.Background(), opts, flag.Arg(0), flag.Args()[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, opts options, command string, args []string) error {
+	dialect, dsn, err := cli.ParseDatabaseURL(opts.databaseURL)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "backups":
+		backups, err := backup.List(opts.backups.Dir)
+		if err != nil {
+			return err
+		}
+		for _, b := range backups {
+			fmt.Printf("%s  %10d  %s\n", b.CreatedAt.Format("2006-01-02 15:04:05"), b.Size, b.Path)
+		}
+		return nil
+	case "restore":
+		if len(args) != 1 {
+			return errors.New("restore needs the backup file")
+		}
+		if dialect != database.SQLite {
+			return errors.New("restore only supports SQLite")
+		}
+		file := database.SQLiteFile(dsn)
+		if !opts.yes && !cli.Confirm(fmt.Sprintf("Replace %s with %s? The API must be stopped.", file, args[0])) {
+			return errors.New("aborted")
+		}
+		restored, err := backup.Restore(ctx, args[0], file)
+		if err != nil {
+			return err
+		}
+		if restored.Previous != "" {
+			fmt.Println("previous database moved to", restored.Previous)
+		}
+		printForeignKeys(restored.Report)
+		fmt.Printf("restored schema version %d\n", restored.SchemaVersion)
+		if restored.SchemaVersion < database.SchemaVersion {
+			fmt.Printf("the schema is older than %d, run the migrations before starting the API\n", database.SchemaVersion)
+		}
+		return nil
+	}
+
+	openDSN := dsn
+	if dialect == database.SQLite {
+		// Wait for the API to release its locks instead of failing.
+		openDSN = database.SQLiteDSN(dsn, opts.database.SQLite.Pragmas(), false)
+	}
+
+	db, err := sql.Open(dialect.DriverName(), openDSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	models := database.NewModels(db, database.Options{Dialect: dialect, Timeouts: opts.database.Timeouts()})
+
+	switch command {
+	case "backup":
+		if dialect != database.SQLite {
+			return errors.New("backup only supports SQLite; use the tools of your database server")
+		}
+		created, err := backup.Create(ctx, db, opts.backups)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("created %s (%d bytes)\n", created.Path, created.Size)
+		return nil
+	case "check":
+		if dialect != database.SQLite {
+			return errors.New("check only supports SQLite")
+		}
+		report, err := backup.Check(ctx, db)
+		if err != nil {
+			return err
+		}
+		for _, message := range report.Integrity {
+			fmt.Println("integrity:", message)
+		}
+		printForeignKeys(report)
+		if !report.OK {
+			return errors.New("the database has problems")
+		}
+		fmt.Println("ok")
+		return nil
+	case "grant", "revoke":
+		if len(args) != 1 {
+			return fmt.Errorf("%s needs the email of a user", command)
+		}
+		user, err := models.Users.GetByEmail(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return models.Users.SetAdmin(ctx, user.ID, command == "grant")
+	default:
+		return fmt.Errorf("unknown command %q, run admin -h for help", command)
+	}
+}
+
+func printForeignKeys(report *backup.Report) {
+	for _, v := range report.ForeignKeys {
+		row := "?"
+		if v.RowID != nil {
+			row = fmt.Sprint(*v.RowID)
+		}
+		fmt.Printf("foreign key: %s row %s references a missing %s row\n", v.Table, row, v.Parent)
+	}
+}
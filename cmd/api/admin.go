@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"rest-api-go-gin/internal/backup"
+	"rest-api-go-gin/internal/database"
+	"rest-api-go-gin/internal/logger"
+	"rest-api-go-gin/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateBackup godoc
+// @Summary Back up the database
+// @Schemes
+// @Description Take a consistent backup of the SQLite database while the API keeps running. Old backups beyond the configured number are removed.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 201 {object} backup.Backup
+// @Failure 401 {object} problem "Unauthorized"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Failure 501 {object} problem "Not Implemented"
+// @Security Bearer
+// @Router /admin/backups [post]
+func (app *application) createBackup(c *gin.Context) {
+	if !app.requireSQLite(c) {
+		return
+	}
+
+	db, err := app.maintenanceDB()
+	if err != nil {
+		app.serverError(c, err, "Failed to back up the database")
+		return
+	}
+	defer db.Close()
+
+	created, err := backup.Create(c.Request.Context(), db, app.backups)
+	if err != nil {
+		app.serverError(c, err, "Failed to back up the database")
+		return
+	}
+
+	logger.FromContext(c.Request.Context()).Info("database backed up",
+		"path", created.Path, "size", created.Size, "user_id", app.GetUserFromContext(c).ID)
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetBackups godoc
+// @Summary List database backups
+// @Schemes
+// @Description List the backups in the backup directory, oldest first
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {array} backup.Backup
+// @Failure 401 {object} problem "Unauthorized"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Security Bearer
+// @Router /admin/backups [get]
+func (app *application) getBackups(c *gin.Context) {
+	backups, err := backup.List(app.backups.Dir)
+	if err != nil {
+		app.serverError(c, err, "Failed to list backups")
+		return
+	}
+
+	c.JSON(http.StatusOK, backups)
+}
+
+// CheckDatabase godoc
+// @Summary Check the database for corruption
+// @Schemes
+// @Description Run PRAGMA integrity_check and PRAGMA foreign_key_check on the SQLite database
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} backup.Report
+// @Failure 401 {object} problem "Unauthorized"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Failure 501 {object} problem "Not Implemented"
+// @Security Bearer
+// @Router /admin/database/check [get]
+func (app *application) checkDatabase(c *gin.Context) {
+	if !app.requireSQLite(c) {
+		return
+	}
+
+	db, err := app.maintenanceDB()
+	if err != nil {
+		app.serverError(c, err, "Failed to check the database")
+		return
+	}
+	defer db.Close()
+
+	report, err := backup.Check(c.Request.Context(), db)
+	if err != nil {
+		app.serverError(c, err, "Failed to check the database")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// maintenanceDB opens a connection of its own to the SQLite database for a
+// backup or an integrity check. Both read the whole database: on the single
+// write connection they would hold up every write while they run, and the
+// read-only pool refuses VACUUM INTO.
+func (app *application) maintenanceDB() (*sql.DB, error) {
+	_, dsn, err := database.ParseURL(app.config.Database.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := tracing.OpenDB(app.dialect.DriverName(), database.SQLiteDSN(dsn, app.config.Database.SQLite.Pragmas(), false))
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	return db, nil
+}
+
+// requireSQLite responds with 501 Not Implemented unless the API runs on
+// SQLite, the only database the backup tools support.
+func (app *application) requireSQLite(c *gin.Context) bool {
+	if app.dialect == database.SQLite {
+		return true
+	}
+
+	app.errorResponse(c, http.StatusNotImplemented, "Backups are only supported for SQLite; use the tools of your database server")
+	return false
+}
@@ -1,9 +1,10 @@
 package main
 
 import (
-	"log"
+	"errors"
 	"net/http"
 	"rest-api-go-gin/internal/database"
+	"rest-api-go-gin/internal/logger"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,7 +15,7 @@ import (
 type registerRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8"`
-	Name     string `json:"name" binding:"required,min=2"`
+	Name     string `json:"name" binding:"required,notblank,min=2"`
 }
 
 type loginRequest struct {
@@ -35,19 +36,22 @@ type loginResponse struct {
 // @Produce json
 // @Param event body registerRequest true "Registeration request"
 // @Success 201 {object} database.User
-// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 409 {object} problem "Conflict"
 // @Router /auth/register [post]
 func (app *application) registerUser(c *gin.Context) {
 	var payload registerRequest
 
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		app.bindingError(c, err, &payload)
 		return
 	}
 
+	_, span := tracer.Start(c.Request.Context(), "bcrypt.GenerateFromPassword")
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(payload.Password), bcrypt.DefaultCost)
+	span.End()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt the password"})
+		app.serverError(c, err, "Failed to encrypt the password")
 		return
 	}
 
@@ -59,12 +63,17 @@ func (app *application) registerUser(c *gin.Context) {
 		Name:     payload.Name,
 	}
 
-	if err := app.models.Users.Insert(&user); err != nil {
-		log.Fatal(err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create a user"})
+	if err := app.models.Users.Insert(c.Request.Context(), &user); err != nil {
+		if errors.Is(err, database.ErrConflict) {
+			app.errorResponse(c, http.StatusConflict, "A user with this email already exists")
+			return
+		}
+		app.modelError(c, err, "Could not create a user")
 		return
 	}
 
+	app.metrics.Registrations.Inc()
+
 	c.JSON(http.StatusCreated, user)
 }
 
@@ -77,36 +86,39 @@ func (app *application) registerUser(c *gin.Context) {
 // @Produce json
 // @Param request body loginRequest true "User login details"
 // @Success 200 {object} loginResponse
-// @Failure 400 {object} map[string]string "Bad Request"
-// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 401 {object} problem "Unauthorized"
+// @Failure 500 {object} problem "Internal Server Error"
 // @Router /auth/login [post]
 func (app *application) login(c *gin.Context) {
 	var payload loginRequest
 	if err := c.ShouldBindJSON(&payload); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		app.bindingError(c, err, &payload)
 		return
 	}
 
-	existingUser, err := app.models.Users.GetByEmail(payload.Email)
-	if existingUser == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+	existingUser, err := app.models.Users.GetByEmail(c.Request.Context(), payload.Email)
+	if errors.Is(err, database.ErrNotFound) {
+		app.metrics.Logins.WithLabelValues("failure").Inc()
+		app.errorResponse(c, http.StatusUnauthorized, "Invalid email or password")
 		return
 	}
-
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		app.modelError(c, err, "Something went wrong")
 		return
 	}
 
+	_, span := tracer.Start(c.Request.Context(), "bcrypt.CompareHashAndPassword")
 	err = bcrypt.CompareHashAndPassword([]byte(existingUser.Password), []byte(payload.Password))
+	span.End()
 	if err != nil {
 		if err == bcrypt.ErrMismatchedHashAndPassword {
-			log.Println("Invalid password provided")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+			logger.FromContext(c.Request.Context()).Info("invalid password provided", "user_id", existingUser.ID)
+			app.metrics.Logins.WithLabelValues("failure").Inc()
+			app.errorResponse(c, http.StatusUnauthorized, "Invalid email or password")
 			return
 		}
-		log.Fatal(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		app.serverError(c, err, "Something went wrong")
 		return
 	}
 
@@ -115,12 +127,14 @@ func (app *application) login(c *gin.Context) {
 		"expr":   time.Now().Add(time.Hour * 72).Unix(),
 	})
 
-	tokenString, err := token.SignedString([]byte(app.jwtSecret))
+	tokenString, err := token.SignedString([]byte(app.config.Auth.JWTSecret))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong while generating token"})
+		app.serverError(c, err, "Something went wrong while generating token")
 		return
 	}
 
+	app.metrics.Logins.WithLabelValues("success").Inc()
+
 	c.JSON(http.StatusOK, loginResponse{Token: tokenString})
 
 }
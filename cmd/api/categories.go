@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"rest-api-go-gin/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCategories godoc
+// @Summary Get all categories
+// @Schemes
+// @Description Get all event categories
+// @Tags Categories
+// @Accept json
+// @Produce json
+// @Success 200 {array} database.Category
+// @Failure 500 {object} problem "Internal Server Error"
+// @Router /categories [get]
+func (app *application) getAllCategories(c *gin.Context) {
+	categories, err := app.models.Categories.GetAll(c.Request.Context())
+	if err != nil {
+		app.modelError(c, err, "Failed to retrieve categories")
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+// CreateCategory godoc
+// @Summary Create a category
+// @Schemes
+// @Description Create new event category. Only administrators may manage categories.
+// @Tags Categories
+// @Accept json
+// @Produce json
+// @Param category body database.Category true "Category object"
+// @Success 201 {object} database.Category
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 401 {object} problem "Unauthorized"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 409 {object} problem "Conflict"
+// @Failure 422 {object} problem "Unprocessable Entity"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Security Bearer
+// @Router /admin/categories [post]
+func (app *application) createCategory(c *gin.Context) {
+	var category database.Category
+
+	if err := c.ShouldBindJSON(&category); err != nil {
+		app.bindingError(c, err, &category)
+		return
+	}
+
+	if err := app.models.Categories.Insert(c.Request.Context(), &category); err != nil {
+		if errors.Is(err, database.ErrConflict) {
+			app.errorResponse(c, http.StatusConflict, "A category with this name already exists")
+			return
+		}
+		if errors.Is(err, database.ErrEmptySlug) {
+			app.errorResponse(c, http.StatusUnprocessableEntity, "The category name must contain at least one ASCII letter or digit")
+			return
+		}
+		app.modelError(c, err, "Failed to create category")
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// GetEventFacets godoc
+// @Summary Get event facet counts
+// @Schemes
+// @Description Count the listed events matching the filter per category and per tag
+// @Tags Events
+// @Accept json
+// @Produce json
+// @Param category query string false "Category slug"
+// @Param tag query []string false "Tag the events must carry; repeat to require several tags" collectionFormat(multi)
+// @Param near query string false "Only events at venues near this point, as lat,lng"
+// @Param radius_km query number false "Search radius around near in kilometres" default(10)
+// @Success 200 {object} database.Facets
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Router /events/facets [get]
+func (app *application) getEventFacets(c *gin.Context) {
+	filter, ok := app.eventFilterFromQuery(c)
+	if !ok {
+		return
+	}
+
+	facets, err := app.models.Events.Facets(c.Request.Context(), filter)
+	if err != nil {
+		app.modelError(c, err, "Failed to retrieve event facets")
+		return
+	}
+
+	c.JSON(http.StatusOK, facets)
+}
+
+// validateCategory checks that the referenced category exists and writes an
+// error response if it does not.
+func (app *application) validateCategory(c *gin.Context, categoryID *int) bool {
+	if categoryID == nil {
+		return true
+	}
+
+	_, err := app.models.Categories.Get(c.Request.Context(), *categoryID)
+	if errors.Is(err, database.ErrNotFound) {
+		app.errorResponse(c, http.StatusBadRequest, "Category not found")
+		return false
+	}
+	if err != nil {
+		app.modelError(c, err, "Failed to retrieve category")
+		return false
+	}
+
+	return true
+}
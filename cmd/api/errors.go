@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"rest-api-go-gin/internal/database"
+	"rest-api-go-gin/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusClientClosedRequest is the non-standard status used when the client
+// went away before the response was ready.
+const statusClientClosedRequest = 499
+
+const problemContentType = "application/problem+json"
+
+// Problem types are relative URIs, so they resolve against the API's own
+// address. They are part of the API contract: clients may switch on them, so
+// existing ones must not change.
+const problemValidation = "/problems/validation-error"
+
+var problemTypes = map[int]string{
+	http.StatusBadRequest:            "/problems/bad-request",
+	http.StatusUnauthorized:          "/problems/unauthorized",
+	http.StatusForbidden:             "/problems/forbidden",
+	http.StatusNotFound:              "/problems/not-found",
+	http.StatusConflict:              "/problems/conflict",
+	http.StatusRequestEntityTooLarge: "/problems/content-too-large",
+	http.StatusUnprocessableEntity:   "/problems/unprocessable-entity",
+	http.StatusTooManyRequests:       "/problems/too-many-requests",
+	statusClientClosedRequest:        "/problems/request-canceled",
+	http.StatusInternalServerError:   "/problems/internal-error",
+	http.StatusNotImplemented:        "/problems/not-implemented",
+	http.StatusServiceUnavailable:    "/problems/service-unavailable",
+}
+
+// problem is an RFC 7807 problem details body. Every error the API returns
+// has this shape.
+type problem struct {
+	Type      string       `json:"type" example:"/problems/not-found"`
+	Title     string       `json:"title" example:"Not Found"`
+	Status    int          `json:"status" example:"404"`
+	Detail    string       `json:"detail,omitempty" example:"Event not found"`
+	Instance  string       `json:"instance,omitempty" example:"/api/v1/events/42"`
+	RequestID string       `json:"requestId,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+	Errors    []fieldError `json:"errors,omitempty"`
+}
+
+// fieldError describes why a single field of the request body was rejected.
+type fieldError struct {
+	Field   string `json:"field" example:"email"`
+	Message string `json:"message" example:"must be a valid email address"`
+}
+
+// errorResponse writes a problem+json body for status with message as its
+// detail. The body carries the request ID, so that a failed request can be
+// matched to its log lines.
+func (app *application) errorResponse(c *gin.Context, status int, message string) {
+	problemType, ok := problemTypes[status]
+	if !ok {
+		problemType = "about:blank"
+	}
+
+	app.writeProblem(c, problem{
+		Type:   problemType,
+		Status: status,
+		Detail: message,
+	})
+}
+
+func (app *application) writeProblem(c *gin.Context, p problem) {
+	p.Title = http.StatusText(p.Status)
+	if p.Status == statusClientClosedRequest {
+		p.Title = "Client Closed Request"
+	}
+	p.Instance = c.Request.URL.Path
+	p.RequestID = c.GetString("requestId")
+
+	c.Header("Content-Type", problemContentType)
+	c.JSON(p.Status, p)
+}
+
+// modelError writes the response for an error returned by a model method.
+// It is the one place where database errors are turned into HTTP statuses:
+// missing rows become 404, duplicates 409 and references to missing rows 422.
+// Database timeouts become 503 so that clients retry and requests the client
+// gave up on become 499. Any other error is passed to serverError.
+func (app *application) modelError(c *gin.Context, err error, message string) {
+	log := logger.FromContext(c.Request.Context())
+
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		app.errorResponse(c, http.StatusNotFound, notFoundMessage(err))
+	case errors.Is(err, database.ErrConflict):
+		app.errorResponse(c, http.StatusConflict, "The resource conflicts with an existing one")
+	case errors.Is(err, database.ErrForeignKey):
+		app.errorResponse(c, http.StatusUnprocessableEntity, "The request refers to a resource that does not exist")
+	case errors.Is(err, database.ErrTimeout):
+		log.Warn("database operation timed out", "error", err)
+		c.Header("Retry-After", "1")
+		app.errorResponse(c, http.StatusServiceUnavailable, "The server is busy, please try again")
+	case errors.Is(err, database.ErrCanceled):
+		log.Info("request canceled by client", "error", err)
+		app.errorResponse(c, statusClientClosedRequest, "Request canceled")
+	default:
+		app.serverError(c, err, message)
+	}
+}
+
+// responseError is an error response decided within a transaction. Handlers
+// return it from the function passed to WithTx, so that the transaction is
+// rolled back, and write it with txError once WithTx has returned.
+type responseError struct {
+	status  int
+	message string
+	// err is the model error that caused the response, if any. modelError
+	// chooses its status, using message for unexpected errors.
+	err error
+}
+
+func (e *responseError) Error() string {
+	if e.err != nil {
+		return e.message + ": " + e.err.Error()
+	}
+	return e.message
+}
+
+func (e *responseError) Unwrap() error {
+	return e.err
+}
+
+// clientError returns a responseError for status with message as its detail.
+func clientError(status int, message string) error {
+	return &responseError{status: status, message: message}
+}
+
+// modelFailure returns a responseError for an error returned by a model
+// method, to be reported like modelError does.
+func modelFailure(err error, message string) error {
+	return &responseError{err: err, message: message}
+}
+
+// txError writes the response for an error returned by WithTx. Errors that
+// are not a responseError come from the transaction itself and are reported
+// with message.
+func (app *application) txError(c *gin.Context, err error, message string) {
+	var responseErr *responseError
+	switch {
+	case !errors.As(err, &responseErr):
+		app.modelError(c, err, message)
+	case responseErr.err != nil:
+		app.modelError(c, responseErr.err, responseErr.message)
+	default:
+		app.errorResponse(c, responseErr.status, responseErr.message)
+	}
+}
+
+// serverError logs an unexpected error and reports it as a 500 with message.
+func (app *application) serverError(c *gin.Context, err error, message string) {
+	logger.FromContext(c.Request.Context()).Error(message, "error", err)
+	app.errorResponse(c, http.StatusInternalServerError, message)
+}
+
+// notFoundMessages are the messages shown to clients for the entities of
+// database.NotFoundError.
+var notFoundMessages = map[string]string{
+	"attendee": "Attendee not found",
+	"category": "Category not found",
+	"event":    "Event not found",
+	"revision": "Revision not found",
+	"user":     "User not found",
+	"venue":    "Venue not found",
+}
+
+// notFoundMessage returns the message shown to clients for an ErrNotFound.
+// It never uses the error's text, which may carry internal details.
+func notFoundMessage(err error) string {
+	var notFound *database.NotFoundError
+	if errors.As(err, &notFound) {
+		if message, ok := notFoundMessages[notFound.Entity]; ok {
+			return message
+		}
+	}
+
+	return "Not found"
+}
@@ -1,59 +1,85 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"rest-api-go-gin/internal/database"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+const maxSearchRadiusKm = 500
+
 // CreateEvent godoc
 // @Summary Create an event
 // @Schemes
-// @Description Create new event
+// @Description Create new event. New events start as drafts and are not listed until published.
 // @Tags Events
 // @Accept json
 // @Produce json
 // @Param event body database.Event true "Event object"
 // @Success 201 {object} database.Event
-// @Failure 400 {object} map[string]string "Bad Request"
-// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 401 {object} problem "Unauthorized"
+// @Failure 500 {object} problem "Internal Server Error"
 // @Security Bearer
 // @Router /events [post]
 func (app *application) createEvent(c *gin.Context) {
 	var event database.Event
 
 	if err := c.ShouldBindJSON(&event); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		app.bindingError(c, err, &event)
 		return
 	}
 
 	user := app.GetUserFromContext(c) // Get current user from context
 	event.OwnerID = user.ID
+	event.Status = database.EventStatusDraft
+	event.PublishAt = nil
+	event.CancelReason = nil
 
-	if err := app.models.Events.Insert(&event); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create event"})
+	if !app.validateCategory(c, event.CategoryID) || !app.resolveVenue(c, &event) {
 		return
 	}
 
+	if err := app.models.Events.Insert(c.Request.Context(), &event); err != nil {
+		app.modelError(c, err, "Failed to create event")
+		return
+	}
+
+	app.metrics.EventsCreated.Inc()
+
 	c.JSON(http.StatusCreated, event)
 }
 
 // GetEvents godoc
 // @Summary Get all events
 // @Schemes
-// @Description do ping
+// @Description Get all published, cancelled and completed events, optionally filtered by category, tags and distance
 // @Tags Events
 // @Accept json
 // @Produce json
+// @Param category query string false "Category slug"
+// @Param tag query []string false "Tag the events must carry; repeat to require several tags" collectionFormat(multi)
+// @Param near query string false "Only events at venues near this point, as lat,lng. Results are sorted by distance."
+// @Param radius_km query number false "Search radius around near in kilometres" default(10)
 // @Success 200 {object} []database.Event
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 500 {object} problem "Internal Server Error"
 // @Router /events [get]
 func (app *application) getAllEvents(c *gin.Context) {
-	events, err := app.models.Events.GetAll()
+	filter, ok := app.eventFilterFromQuery(c)
+	if !ok {
+		return
+	}
+
+	events, err := app.models.Events.GetAll(c.Request.Context(), filter)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+		app.modelError(c, err, "Failed to retrieve events")
 		return
 	}
 
@@ -63,33 +89,44 @@ func (app *application) getAllEvents(c *gin.Context) {
 // GetEvent godoc
 // @Summary Get an event By ID
 // @Schemes
-// @Description Get an event By ID
+// @Description Get an event By ID. Drafts and scheduled events are only found by their owner.
 // @Tags Events
 // @Accept json
 // @Produce json
 // @Param id path int true "Event ID"
 // @Success 201 {object} database.Event
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 500 {object} problem "Internal Server Error"
 // @Router /events/{id} [get]
 func (app *application) getEvent(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		app.errorResponse(c, http.StatusBadRequest, "Invalid event ID")
 		return
 	}
 
-	event, err := app.models.Events.Get(id)
-	if event == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+	event, err := app.models.Events.Get(c.Request.Context(), id)
+	if err != nil {
+		app.modelError(c, err, "Failed to retrieve event")
 		return
 	}
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+	if !app.canView(c, event) {
+		app.errorResponse(c, http.StatusNotFound, "Event not found")
 		return
 	}
 
 	c.JSON(http.StatusOK, event)
 }
 
+// canView reports whether the current user may see the event. Drafts and
+// scheduled events are only shown to their owner and administrators; to
+// everyone else they do not exist, so their IDs cannot be found by trying
+// them in turn.
+func (app *application) canView(c *gin.Context, event *database.Event) bool {
+	return event.VisibleTo(app.GetUserFromContext(c))
+}
+
 // UpdateEvent godoc
 // @Summary Update an event
 // @Schemes
@@ -100,45 +137,57 @@ func (app *application) getEvent(c *gin.Context) {
 // @Param event body database.Event true "Event object"
 // @Param id path int true "Event ID"
 // @Success 201 {object} database.Event
-// @Failure 400 {object} map[string]string "Bad Request"
-// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 401 {object} problem "Unauthorized"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 500 {object} problem "Internal Server Error"
 // @Security Bearer
 // @Router /events/{id} [put]
 func (app *application) updateEvent(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		app.errorResponse(c, http.StatusBadRequest, "Invalid event ID")
 		return
 	}
 
 	user := app.GetUserFromContext(c) // Get current user from the context
-	existingEvent, err := app.models.Events.Get(id)
-	if existingEvent == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
-		return
-	}
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+	updatedEvent := &database.Event{}
+
+	if err := c.ShouldBindJSON(updatedEvent); err != nil {
+		app.bindingError(c, err, updatedEvent)
 		return
 	}
 
-	// Check if user has permission to update the event
-	if existingEvent.OwnerID != user.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to update this event"})
+	if !app.validateCategory(c, updatedEvent.CategoryID) || !app.resolveVenue(c, updatedEvent) {
 		return
 	}
 
-	updatedEvent := &database.Event{}
+	ctx := c.Request.Context()
+	err = app.models.WithTx(ctx, func(tx database.Models) error {
+		existingEvent, err := tx.Events.Get(ctx, id)
+		if err != nil {
+			return modelFailure(err, "Failed to retrieve event")
+		}
 
-	if err := c.ShouldBindJSON(updatedEvent); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
+		// Check if user has permission to update the event
+		if existingEvent.OwnerID != user.ID {
+			return clientError(http.StatusForbidden, "You are not authorized to update this event")
+		}
 
-	updatedEvent.ID = id
+		updatedEvent.ID = id
+		updatedEvent.OwnerID = existingEvent.OwnerID
+		updatedEvent.Status = existingEvent.Status
+		updatedEvent.PublishAt = existingEvent.PublishAt
+		updatedEvent.CancelReason = existingEvent.CancelReason
 
-	if err := app.models.Events.Update(updatedEvent); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
+		if err := tx.Events.Update(ctx, updatedEvent, user.ID); err != nil {
+			return modelFailure(err, "Failed to update event")
+		}
+		return nil
+	})
+	if err != nil {
+		app.txError(c, err, "Failed to update event")
 		return
 	}
 
@@ -149,44 +198,45 @@ func (app *application) updateEvent(c *gin.Context) {
 // DeleteEvent godoc
 // @Summary Delete an event
 // @Schemes
-// @Description Delete an existing event
+// @Description Move an existing event to the trash. It can be restored until it is purged.
 // @Tags Events
 // @Accept json
 // @Produce json
 // @Param id path int true "Event ID"
 // @Success 204 {object} nil "No Content"
-// @Failure 400 {object} map[string]string "Bad Request"
-// @Failure 403 {object} map[string]string "Forbidden"
-// @Failure 404 {object} map[string]string "Not Found"
-// @Failure 500 {object} map[string]string "Internal Server"
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 500 {object} problem "Internal Server Error"
 // @Security Bearer
 // @Router /events/{id} [delete]
 func (app *application) deleteEvent(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		app.errorResponse(c, http.StatusBadRequest, "Invalid event ID")
 		return
 	}
 
 	user := app.GetUserFromContext(c) // Get current user from the context
-	existingEvent, err := app.models.Events.Get(id)
-	if existingEvent == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
-		return
-	}
+	ctx := c.Request.Context()
+	err = app.models.WithTx(ctx, func(tx database.Models) error {
+		existingEvent, err := tx.Events.Get(ctx, id)
+		if err != nil {
+			return modelFailure(err, "Failed to retrieve event")
+		}
+
+		// Check if user has permission to update the event
+		if existingEvent.OwnerID != user.ID {
+			return clientError(http.StatusForbidden, "You are not authorized to delete this event")
+		}
+
+		if err := tx.Events.Delete(ctx, id, user.ID); err != nil {
+			return modelFailure(err, "Failed to delete event")
+		}
+		return nil
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
-		return
-	}
-
-	// Check if user has permission to update the event
-	if existingEvent.OwnerID != user.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to delete this event"})
-		return
-	}
-
-	if err := app.models.Events.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete event"})
+		app.txError(c, err, "Failed to delete event")
 		return
 	}
 
@@ -203,72 +253,72 @@ func (app *application) deleteEvent(c *gin.Context) {
 // @Param id path int true "Event ID"
 // @Param userId path int true "User ID"
 // @Success 201 {object} database.Attendee
-// @Failure 400 {object} map[string]string "Bad Request"
-// @Failure 403 {object} map[string]string "Forbidden"
-// @Failure 404 {object} map[string]string "Not Found"
-// @Failure 500 {object} map[string]string "Internal Server"
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 409 {object} problem "Conflict"
+// @Failure 500 {object} problem "Internal Server Error"
 // @Security Bearer
 // @Router /events/{id}/attendees/{userId} [post]
 func (app *application) addAttendeeToEvent(c *gin.Context) {
 	eventID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event id"})
+		app.errorResponse(c, http.StatusBadRequest, "Invalid event id")
 		return
 	}
 
 	userID, err := strconv.Atoi(c.Param("userId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		app.errorResponse(c, http.StatusBadRequest, "Invalid user id")
 		return
 	}
 
 	user := app.GetUserFromContext(c) // Get current user from the context
-	event, err := app.models.Events.Get(eventID)
+	ctx := c.Request.Context()
+
+	// The checks and the insert share a transaction, so that concurrent
+	// requests cannot both add the same attendee.
+	var attendee database.Attendee
+	err = app.models.WithTx(ctx, func(tx database.Models) error {
+		event, err := tx.Events.Get(ctx, eventID)
+		if err != nil {
+			return modelFailure(err, "Failed to retrieve event")
+		}
+
+		// Check if user has permission to update the event
+		if event.OwnerID != user.ID {
+			return clientError(http.StatusForbidden, "You are not authorized to add an attendee")
+		}
+
+		userToAdd, err := tx.Users.GetByID(ctx, userID)
+		if err != nil {
+			return modelFailure(err, "Failed to retrieve user")
+		}
+
+		_, err = tx.Attendees.GetByEventAndAttendee(ctx, event.ID, userToAdd.ID)
+		if err == nil {
+			return clientError(http.StatusConflict, "Attendee already exists")
+		}
+		if !errors.Is(err, database.ErrNotFound) {
+			return modelFailure(err, "Failed to retrieve attendee")
+		}
+
+		attendee = database.Attendee{
+			EventID: event.ID,
+			UserID:  userToAdd.ID,
+		}
+
+		if _, err := tx.Attendees.Insert(ctx, &attendee, user.ID); err != nil {
+			return modelFailure(err, "Failed to add attendee")
+		}
+		return nil
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
-		return
-	}
-	if event == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		app.txError(c, err, "Failed to add attendee")
 		return
 	}
 
-	// Check if user has permission to update the event
-	if event.OwnerID != user.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to add an attendee"})
-		return
-	}
-
-	userToAdd, err := app.models.Users.GetByID(userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
-		return
-	}
-	if userToAdd == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		return
-	}
-
-	existingAttendee, err := app.models.Attendees.GetByEventAndAttendee(event.ID, userToAdd.ID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attendee"})
-		return
-	}
-	if existingAttendee != nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Attendee already exists"})
-		return
-	}
-
-	attendee := database.Attendee{
-		EventID: event.ID,
-		UserID:  userToAdd.ID,
-	}
-
-	_, err = app.models.Attendees.Insert(&attendee)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add attendee"})
-		return
-	}
+	app.metrics.RSVPs.Inc()
 
 	c.JSON(http.StatusCreated, attendee)
 
@@ -283,17 +333,30 @@ func (app *application) addAttendeeToEvent(c *gin.Context) {
 // @Produce json
 // @Param id path int true "Event ID"
 // @Success 200 {array} database.User
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 500 {object} problem "Internal Server Error"
 // @Router /events/{id}/attendees [get]
 func (app *application) getAttendeesForEvent(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event id"})
+		app.errorResponse(c, http.StatusBadRequest, "Invalid event id")
+		return
+	}
+
+	event, err := app.models.Events.Get(c.Request.Context(), id)
+	if err != nil {
+		app.modelError(c, err, "Failed to retrieve event")
+		return
+	}
+	if !app.canView(c, event) {
+		app.errorResponse(c, http.StatusNotFound, "Event not found")
 		return
 	}
 
-	attendees, err := app.models.Attendees.GetAttendeesByEvent(id)
+	attendees, err := app.models.Attendees.GetAttendeesByEvent(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attendees for event"})
+		app.modelError(c, err, "Failed to retrieve attendees for event")
 		return
 	}
 
@@ -310,45 +373,45 @@ func (app *application) getAttendeesForEvent(c *gin.Context) {
 // @Param id path int true "Event ID"
 // @Param userId path int true "User ID"
 // @Success 204 {object} nil "No Content"
-// @Failure 400 {object} map[string]string "Bad Request"
-// @Failure 403 {object} map[string]string "Forbidden"
-// @Failure 404 {object} map[string]string "Not Found"
-// @Failure 500 {object} map[string]string "Internal Server"
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 500 {object} problem "Internal Server Error"
 // @Security Bearer
 // @Router /events/{id}/attendees/{userId} [delete]
 func (app *application) deleteAttendeeFromEvent(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event id"})
+		app.errorResponse(c, http.StatusBadRequest, "Invalid event id")
 		return
 	}
 
 	userID, err := strconv.Atoi(c.Param("userId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		app.errorResponse(c, http.StatusBadRequest, "Invalid user id")
 		return
 	}
 
 	user := app.GetUserFromContext(c) // Get current user from the context
-	existingEvent, err := app.models.Events.Get(id)
-	if existingEvent == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
-		return
-	}
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
-		return
-	}
-
-	// Check if user has permission to update the event
-	if existingEvent.OwnerID != user.ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to delete an attendee from event"})
-		return
-	}
-
-	err = app.models.Attendees.Delete(id, userID)
+	ctx := c.Request.Context()
+	err = app.models.WithTx(ctx, func(tx database.Models) error {
+		existingEvent, err := tx.Events.Get(ctx, id)
+		if err != nil {
+			return modelFailure(err, "Failed to retrieve event")
+		}
+
+		// Check if user has permission to update the event
+		if existingEvent.OwnerID != user.ID {
+			return clientError(http.StatusForbidden, "You are not authorized to delete an attendee from event")
+		}
+
+		if err := tx.Attendees.Delete(ctx, id, userID, user.ID); err != nil {
+			return modelFailure(err, "Failed to delete attendee for event")
+		}
+		return nil
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attendee for event"})
+		app.txError(c, err, "Failed to delete attendee for event")
 		return
 	}
 
@@ -358,25 +421,62 @@ func (app *application) deleteAttendeeFromEvent(c *gin.Context) {
 // GetEventsByAttendee godoc
 // @Summary Get all events by attendee
 // @Schemes
-// @Description Get all events by attendee
+// @Description Get all events by attendee. Drafts and scheduled events are only listed for their owner and administrators.
 // @Tags Attendees
 // @Accept json
 // @Produce json
 // @Param id path int true "Attendee ID"
 // @Success 200 {array} database.Event
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 500 {object} problem "Internal Server Error"
 // @Router /attendees/{id}/events [get]
 func (app *application) getEventsByAttendee(c *gin.Context) {
 	attendeeID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attendee id"})
+		app.errorResponse(c, http.StatusBadRequest, "Invalid attendee id")
 		return
 	}
 
-	events, err := app.models.Events.GetByAttendee(attendeeID)
+	events, err := app.models.Events.GetByAttendee(c.Request.Context(), attendeeID, app.GetUserFromContext(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events for attendee"})
+		app.modelError(c, err, "Failed to retrieve events for attendee")
 		return
 	}
 
 	c.JSON(http.StatusOK, events)
 }
+
+// eventFilterFromQuery reads the event listing filter from the query string.
+// It writes an error response and returns false if the filter is invalid.
+func (app *application) eventFilterFromQuery(c *gin.Context) (database.EventFilter, bool) {
+	filter := database.EventFilter{
+		Category: c.Query("category"),
+		Tags:     c.QueryArray("tag"),
+	}
+
+	near := c.Query("near")
+	if near == "" {
+		return filter, true
+	}
+
+	lat, lng, found := strings.Cut(near, ",")
+	latitude, latErr := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+	longitude, lngErr := strconv.ParseFloat(strings.TrimSpace(lng), 64)
+	if !found || latErr != nil || lngErr != nil ||
+		latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		app.errorResponse(c, http.StatusBadRequest, "Invalid near parameter, expected lat,lng")
+		return filter, false
+	}
+
+	radius, err := strconv.ParseFloat(c.DefaultQuery("radius_km", "10"), 64)
+	if err != nil || radius <= 0 || radius > maxSearchRadiusKm {
+		app.errorResponse(c, http.StatusBadRequest,
+			fmt.Sprintf("Invalid radius_km parameter, expected a number between 0 and %d", maxSearchRadiusKm))
+		return filter, false
+	}
+
+	filter.Near = &database.GeoPoint{Latitude: latitude, Longitude: longitude}
+	filter.RadiusKm = radius
+
+	return filter, true
+}
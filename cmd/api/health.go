@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"rest-api-go-gin/internal/health"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Build information, set at build time with
+// -ldflags "-X main.version=... -X main.commit=... -X main.buildTime=...".
+// When left empty they are filled from the module's build info.
+var (
+	version   string
+	commit    string
+	buildTime string
+)
+
+type livenessResponse struct {
+	Status string `json:"status"`
+	Uptime string `json:"uptime"`
+}
+
+type readinessResponse struct {
+	Status string          `json:"status"`
+	Checks []health.Result `json:"checks"`
+}
+
+type versionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	Modified  bool   `json:"modified"`
+	GoVersion string `json:"goVersion"`
+}
+
+var startedAt = time.Now()
+
+// Liveness godoc
+// @Summary Liveness probe
+// @Schemes
+// @Description Report that the process is running
+// @Tags Health
+// @Produce json
+// @Success 200 {object} livenessResponse
+// @Router /healthz [get]
+func (app *application) liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, livenessResponse{
+		Status: health.StatusOK,
+		Uptime: time.Since(startedAt).Round(time.Second).String(),
+	})
+}
+
+// Readiness godoc
+// @Summary Readiness probe
+// @Schemes
+// @Description Report whether the server accepts traffic, with the status and latency of every dependency check. Fails as soon as shutdown starts.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} readinessResponse
+// @Failure 503 {object} readinessResponse
+// @Router /readyz [get]
+func (app *application) readiness(c *gin.Context) {
+	if !app.ready.Load() {
+		c.JSON(http.StatusServiceUnavailable, readinessResponse{
+			Status: health.StatusFail,
+			Checks: []health.Result{{Name: "server", Status: health.StatusFail, Error: "shutting down"}},
+		})
+		return
+	}
+
+	report := app.health.Run(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, readinessResponse{Status: report.Status, Checks: report.Checks})
+}
+
+// Version godoc
+// @Summary Build information
+// @Schemes
+// @Description Report the version, git commit, build time and Go version of the running binary
+// @Tags Health
+// @Produce json
+// @Success 200 {object} versionResponse
+// @Router /version [get]
+func (app *application) getVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, buildInfo())
+}
+
+func buildInfo() versionResponse {
+	info := versionResponse{
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.GoVersion = bi.GoVersion
+
+		if info.Version == "" {
+			info.Version = bi.Main.Version
+		}
+
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	return info
+}
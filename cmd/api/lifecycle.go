@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"rest-api-go-gin/internal/database"
+	"rest-api-go-gin/internal/notify"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type scheduleRequest struct {
+	PublishAt time.Time `json:"publishAt" binding:"required"`
+}
+
+type cancelRequest struct {
+	Reason string `json:"reason" binding:"required,notblank,min=3"`
+}
+
+// PublishEvent godoc
+// @Summary Publish an event
+// @Schemes
+// @Description Publish a draft or scheduled event immediately
+// @Tags Lifecycle
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Success 200 {object} database.Event
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 409 {object} problem "Conflict"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Security Bearer
+// @Router /events/{id}/publish [post]
+func (app *application) publishEvent(c *gin.Context) {
+	app.transitionEvent(c, database.StatusChange{Status: database.EventStatusPublished})
+}
+
+// ScheduleEvent godoc
+// @Summary Schedule an event for publication
+// @Schemes
+// @Description Publish a draft event automatically at a future time
+// @Tags Lifecycle
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Param request body scheduleRequest true "Publication time"
+// @Success 200 {object} database.Event
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 409 {object} problem "Conflict"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Security Bearer
+// @Router /events/{id}/schedule [post]
+func (app *application) scheduleEvent(c *gin.Context) {
+	var payload scheduleRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		app.bindingError(c, err, &payload)
+		return
+	}
+
+	if !payload.PublishAt.After(time.Now()) {
+		app.errorResponse(c, http.StatusBadRequest, "Publication time must be in the future")
+		return
+	}
+
+	app.transitionEvent(c, database.StatusChange{
+		Status:    database.EventStatusScheduled,
+		PublishAt: &payload.PublishAt,
+	})
+}
+
+// UnscheduleEvent godoc
+// @Summary Unschedule an event
+// @Schemes
+// @Description Move a scheduled event back to draft
+// @Tags Lifecycle
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Success 200 {object} database.Event
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 409 {object} problem "Conflict"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Security Bearer
+// @Router /events/{id}/unschedule [post]
+func (app *application) unscheduleEvent(c *gin.Context) {
+	app.transitionEvent(c, database.StatusChange{Status: database.EventStatusDraft})
+}
+
+// CancelEvent godoc
+// @Summary Cancel an event
+// @Schemes
+// @Description Cancel an event. It stays visible with the reason and its attendees are notified.
+// @Tags Lifecycle
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Param request body cancelRequest true "Cancellation reason"
+// @Success 200 {object} database.Event
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 409 {object} problem "Conflict"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Security Bearer
+// @Router /events/{id}/cancel [post]
+func (app *application) cancelEvent(c *gin.Context) {
+	var payload cancelRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		app.bindingError(c, err, &payload)
+		return
+	}
+
+	event := app.transitionEvent(c, database.StatusChange{
+		Status:       database.EventStatusCancelled,
+		CancelReason: payload.Reason,
+	})
+	if event == nil {
+		return
+	}
+
+	// Notifications are sent after the response, so they must outlive the
+	// request context while staying part of its trace.
+	ctx := context.WithoutCancel(c.Request.Context())
+	app.background(func() {
+		app.notifyAttendees(ctx, event, fmt.Sprintf("%s has been cancelled", event.Name), payload.Reason)
+	})
+}
+
+// CompleteEvent godoc
+// @Summary Complete an event
+// @Schemes
+// @Description Mark a published event as completed
+// @Tags Lifecycle
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Success 200 {object} database.Event
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 409 {object} problem "Conflict"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Security Bearer
+// @Router /events/{id}/complete [post]
+func (app *application) completeEvent(c *gin.Context) {
+	app.transitionEvent(c, database.StatusChange{Status: database.EventStatusCompleted})
+}
+
+// transitionEvent applies a status change to the event in the request path on
+// behalf of its owner and writes the response. It returns the updated event,
+// or nil if the change was rejected.
+func (app *application) transitionEvent(c *gin.Context, change database.StatusChange) *database.Event {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		app.errorResponse(c, http.StatusBadRequest, "Invalid event ID")
+		return nil
+	}
+
+	user := app.GetUserFromContext(c) // Get current user from the context
+	ctx := c.Request.Context()
+
+	var event *database.Event
+	err = app.models.WithTx(ctx, func(tx database.Models) error {
+		existingEvent, err := tx.Events.Get(ctx, id)
+		if err != nil {
+			return modelFailure(err, "Failed to retrieve event")
+		}
+
+		if existingEvent.OwnerID != user.ID {
+			return clientError(http.StatusForbidden, "You are not authorized to change the status of this event")
+		}
+
+		event, err = tx.Events.Transition(ctx, id, user.ID, change)
+		if errors.Is(err, database.ErrInvalidTransition) {
+			return clientError(http.StatusConflict,
+				fmt.Sprintf("Cannot change event status from %s to %s", existingEvent.Status, change.Status))
+		}
+		if err != nil {
+			return modelFailure(err, "Failed to change event status")
+		}
+		return nil
+	})
+	if err != nil {
+		app.txError(c, err, "Failed to change event status")
+		return nil
+	}
+
+	c.JSON(http.StatusOK, event)
+	return event
+}
+
+// notifyAttendees sends a notification to every attendee of the event.
+func (app *application) notifyAttendees(ctx context.Context, event *database.Event, subject, body string) {
+	attendees, err := app.models.Attendees.GetAttendeesByEvent(ctx, event.ID)
+	if err != nil {
+		app.logger.Error("failed to load attendees for notification", "event_id", event.ID, "error", err)
+		return
+	}
+
+	for _, attendee := range attendees {
+		err := app.notifier.Notify(ctx, notify.Notification{
+			UserID:  attendee.ID,
+			Email:   attendee.Email,
+			Subject: subject,
+			Body:    body,
+		})
+		if err != nil {
+			app.logger.Error("failed to notify attendee", "user_id", attendee.ID, "event_id", event.ID, "error", err)
+		}
+	}
+}
+
+// publishScheduledEvents periodically publishes scheduled events whose
+// publication time has arrived until ctx is cancelled.
+func (app *application) publishScheduledEvents(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		published, err := app.models.Events.PublishDue(ctx, time.Now())
+		if err != nil {
+			app.logger.Error("failed to publish scheduled events", "error", err)
+		}
+		for _, event := range published {
+			app.logger.Info("published scheduled event", slog.Int("event_id", event.ID))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,21 +1,57 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"rest-api-go-gin/internal/backup"
+	"rest-api-go-gin/internal/config"
 	"rest-api-go-gin/internal/database"
-	"rest-api-go-gin/internal/env"
+	"rest-api-go-gin/internal/health"
+	"rest-api-go-gin/internal/logger"
+	"rest-api-go-gin/internal/metrics"
+	"rest-api-go-gin/internal/notify"
+	"rest-api-go-gin/internal/ratelimit"
+	"rest-api-go-gin/internal/tracing"
+	"rest-api-go-gin/internal/validation"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	_ "rest-api-go-gin/docs"
 
+	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/joho/godotenv/autoload" // used to load environment vars
+	"go.opentelemetry.io/otel"
 	_ "modernc.org/sqlite"
 )
 
+const serviceName = "rest-api-go-gin"
+
+var tracer = otel.Tracer("rest-api-go-gin/cmd/api")
+
 type application struct {
-	port      int
-	jwtSecret string
-	models    database.Models
+	config       *config.Config
+	dialect      database.Dialect
+	backups      backup.Options
+	models       database.Models
+	notifier     notify.Notifier
+	health       *health.Registry
+	logger       *slog.Logger
+	metrics      *metrics.Metrics
+	translations *validation.Translations
+	// rateLimits keeps the rate limit buckets. It is nil if rate limiting is
+	// disabled.
+	rateLimits ratelimit.Store
+
+	// ready reports whether the server accepts traffic. It is cleared as
+	// soon as shutdown starts.
+	ready atomic.Bool
+	// wg tracks background goroutines that must finish before shutdown.
+	wg sync.WaitGroup
 }
 
 // @title           Go Gin REST API
@@ -43,21 +79,186 @@ type application struct {
 // @externalDocs.description  OpenAPI
 // @externalDocs.url          https://swagger.io/resources/open-api/
 func main() {
-	db, err := sql.Open("sqlite", "./data.db")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration, with secrets redacted, and exit")
+
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("failed to load configuration", "error", err)
+		os.Exit(2)
+	}
+
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			slog.Error("failed to print configuration", "error", err)
+			os.Exit(1)
+		}
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "\ninvalid configuration:\n%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := cfg.Validate(); err != nil {
+		slog.Error("invalid configuration", "error", err)
+		os.Exit(1)
 	}
 
-	defer db.Close()
+	log, err := logger.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		slog.Error("failed to configure logging", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(log)
+
+	if cfg.Env == config.Development && cfg.Auth.JWTSecret == config.DefaultJWTSecret {
+		log.Warn("using the default JWT secret, which is only safe for development")
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter, serviceName, version)
+	if err != nil {
+		log.Error("failed to configure tracing", "error", err)
+		os.Exit(1)
+	}
+
+	translations, err := setupValidation()
+	if err != nil {
+		log.Error("failed to configure validation", "error", err)
+		os.Exit(1)
+	}
+
+	dialect, dsn, err := database.ParseURL(cfg.Database.URL)
+	if err != nil {
+		log.Error("invalid database URL", "error", err)
+		os.Exit(1)
+	}
+
+	if cfg.Database.AutoMigrate {
+		from, err := database.MigrateUp(dialect, dsn)
+		if err != nil {
+			log.Error("failed to migrate database", "error", err)
+			os.Exit(1)
+		}
+		if from != database.SchemaVersion {
+			log.Info("database migrated", "from", from, "to", database.SchemaVersion)
+		}
+	}
+
+	db, readDB, err := openDB(dialect, dsn, cfg.Database)
+	if err != nil {
+		log.Error("failed to open database", "error", err)
+		os.Exit(1)
+	}
+
+	if err := database.CheckSchemaNotNewer(context.Background(), db); err != nil {
+		log.Error("database schema is newer than this build", "error", err)
+		os.Exit(1)
+	}
+
+	appMetrics := metrics.New(db)
+	if readDB != db {
+		appMetrics.RegisterDB(readDB, "read")
+	}
+
+	models := database.NewModels(db, database.Options{
+		Dialect:  dialect,
+		Timeouts: cfg.Database.Timeouts(),
+		Observer: appMetrics.ObserveQuery,
+		ReadDB:   readDB,
+	})
 
-	models := database.NewModels(db)
 	app := &application{
-		port:      env.GetEnvInt("PORT", 8080),
-		jwtSecret: env.GetEnvString("JWT_SECRET", "some-secret-123456"),
-		models:    models,
+		config:       cfg,
+		dialect:      dialect,
+		models:       models,
+		notifier:     notify.LogNotifier{},
+		health:       health.NewRegistry(cfg.Health.CheckTimeout),
+		logger:       log,
+		metrics:      appMetrics,
+		translations: translations,
+		backups: backup.Options{
+			Dir:  cfg.Backup.Dir,
+			Gzip: cfg.Backup.Gzip,
+			Keep: cfg.Backup.Keep,
+		},
+	}
+
+	if cfg.RateLimit.Enabled {
+		switch cfg.RateLimit.Store {
+		case config.StoreDatabase:
+			app.rateLimits = ratelimit.NewSQLStore(db, dialect)
+		default:
+			app.rateLimits = ratelimit.NewMemoryStore()
+		}
+	}
+
+	// Readiness is checked on the read pool: the single SQLite write
+	// connection is busy for as long as a write runs.
+	app.health.Register("database", readDB.PingContext)
+	app.health.Register("migrations", func(ctx context.Context) error {
+		return database.CheckSchemaVersion(ctx, readDB)
+	})
+
+	err = app.serve()
+
+	if readDB != db {
+		if closeErr := readDB.Close(); closeErr != nil {
+			log.Error("failed to close database", "error", closeErr)
+		}
+	}
+	if closeErr := db.Close(); closeErr != nil {
+		log.Error("failed to close database", "error", closeErr)
+	}
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if flushErr := shutdownTracing(flushCtx); flushErr != nil {
+		log.Error("failed to flush traces", "error", flushErr)
+	}
+
+	if err != nil {
+		log.Error("server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// openDB opens the connection pools of the database. SQLite gets a single
+// write connection and a separate pool of read-only connections, both with
+// the configured pragmas. Other databases use one pool for both.
+func openDB(dialect database.Dialect, dsn string, cfg config.Database) (db, readDB *sql.DB, err error) {
+	pool := cfg.Pool()
+
+	if dialect != database.SQLite {
+		db, err = tracing.OpenDB(dialect.DriverName(), dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		pool.Apply(db)
+
+		return db, db, nil
+	}
+
+	pragmas := cfg.SQLite.Pragmas()
+
+	db, err = tracing.OpenDB(dialect.DriverName(), database.SQLiteDSN(dsn, pragmas, false))
+	if err != nil {
+		return nil, nil, err
 	}
+	database.SQLiteWriterPool.Apply(db)
 
-	if err := app.serve(); err != nil {
-		log.Fatal(err)
+	// The writer switches the journal mode, which readers cannot do.
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, nil, err
 	}
+
+	readDB, err = tracing.OpenDB(dialect.DriverName(), database.SQLiteDSN(dsn, pragmas, true))
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	pool.Apply(readDB)
+
+	return db, readDB, nil
 }
@@ -1,25 +1,148 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"rest-api-go-gin/internal/config"
+	"rest-api-go-gin/internal/database"
+	"rest-api-go-gin/internal/logger"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const requestIDHeader = "X-Request-ID"
+
+// RequestIDMiddleware propagates the caller's X-Request-ID, or generates one,
+// echoes it in the response and attaches it to the request's logger together
+// with the trace ID.
+func (app *application) RequestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		ctx.Set("requestId", requestID)
+		ctx.Header(requestIDHeader, requestID)
+
+		requestLogger := app.logger.With(slog.String("request_id", requestID))
+		if spanContext := trace.SpanContextFromContext(ctx.Request.Context()); spanContext.HasTraceID() {
+			requestLogger = requestLogger.With(slog.String("trace_id", spanContext.TraceID().String()))
+		}
+		ctx.Request = ctx.Request.WithContext(logger.WithContext(ctx.Request.Context(), requestLogger))
+
+		ctx.Next()
+	}
+}
+
+// AccessLogMiddleware writes one log line per request once it is handled.
+func (app *application) AccessLogMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Request.Method),
+			slog.String("route", ctx.FullPath()),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", ctx.Writer.Size()),
+			slog.String("client_ip", ctx.ClientIP()),
+		}
+
+		if user := app.GetUserFromContext(ctx); user.ID != 0 {
+			attrs = append(attrs, slog.Int("user_id", user.ID))
+		}
+
+		if len(ctx.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", ctx.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		logger.FromContext(ctx.Request.Context()).LogAttrs(ctx.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// MetricsMiddleware records the count and latency of every request, labelled
+// by route template and status.
+func (app *application) MetricsMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		app.metrics.ObserveRequest(ctx.Request.Method, route, strconv.Itoa(ctx.Writer.Status()), time.Since(start))
+	}
+}
+
+// RecoveryMiddleware turns a panic in a handler into a 500 response and logs
+// it with the request ID.
+func (app *application) RecoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(ctx *gin.Context, err any) {
+		logger.FromContext(ctx.Request.Context()).Error("panic recovered", slog.String("error", fmt.Sprint(err)))
+		app.errorResponse(ctx, http.StatusInternalServerError, "Something went wrong")
+		ctx.Abort()
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
 func (app *application) AuthMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authHeader := ctx.GetHeader("Authorization")
 		if authHeader == "" {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+			app.errorResponse(ctx, http.StatusUnauthorized, "Authorization header is required")
 			ctx.Abort()
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == authHeader {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Bearer token is required"})
+			app.errorResponse(ctx, http.StatusUnauthorized, "Bearer token is required")
 			ctx.Abort()
 			return
 		}
@@ -28,27 +151,32 @@ func (app *application) AuthMiddleware() gin.HandlerFunc {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, jwt.ErrSignatureInvalid
 			}
-			return []byte(app.jwtSecret), nil
+			return []byte(app.config.Auth.JWTSecret), nil
 		})
 
 		if err != nil || !token.Valid {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			app.errorResponse(ctx, http.StatusUnauthorized, "Invalid token")
 			ctx.Abort()
 			return
 		}
 
 		claims, ok := token.Claims.(jwt.MapClaims)
 		if !ok {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			app.errorResponse(ctx, http.StatusUnauthorized, "Invalid token")
 			ctx.Abort()
 			return
 		}
 
 		userID := claims["userId"].(float64)
 
-		user, err := app.models.Users.GetByID(int(userID))
+		user, err := app.models.Users.GetByID(ctx.Request.Context(), int(userID))
+		if errors.Is(err, database.ErrNotFound) {
+			app.errorResponse(ctx, http.StatusUnauthorized, "Unauthorized access")
+			ctx.Abort()
+			return
+		}
 		if err != nil {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+			app.modelError(ctx, err, "Something went wrong")
 			ctx.Abort()
 			return
 		}
@@ -59,3 +187,174 @@ func (app *application) AuthMiddleware() gin.HandlerFunc {
 
 	}
 }
+
+// OptionalAuthMiddleware authenticates requests that carry an Authorization
+// header like AuthMiddleware and lets anonymous requests through, for public
+// routes that show the owner more.
+func (app *application) OptionalAuthMiddleware() gin.HandlerFunc {
+	auth := app.AuthMiddleware()
+
+	return func(ctx *gin.Context) {
+		if ctx.GetHeader("Authorization") == "" {
+			ctx.Next()
+			return
+		}
+
+		auth(ctx)
+	}
+}
+
+// AdminMiddleware only lets administrators through. It must run after
+// AuthMiddleware.
+func (app *application) AdminMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !app.GetUserFromContext(ctx).IsAdmin {
+			app.errorResponse(ctx, http.StatusForbidden, "Administrator access is required")
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// Headers of the CORS policy. Browsers may send the allowed headers with any
+// method of the API and let scripts read the exposed ones.
+const (
+	corsAllowedMethods = "GET, POST, PUT, DELETE"
+	corsAllowedHeaders = "Accept-Language, Authorization, Content-Type, " + apiKeyHeader + ", " + requestIDHeader
+	corsExposedHeaders = "Content-Language, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, " + requestIDHeader
+)
+
+// CORSMiddleware lets browsers on the allowed origins call the API. It answers
+// preflight requests itself, which browsers cache for the configured max age,
+// and rejects preflights from other origins with 403.
+func (app *application) CORSMiddleware() gin.HandlerFunc {
+	cors := app.config.CORS
+	allowed := map[string]bool{}
+	for _, origin := range cors.Origins() {
+		allowed[origin] = true
+	}
+	anyOrigin := allowed[config.AnyOrigin]
+	maxAge := strconv.Itoa(int(cors.MaxAge.Seconds()))
+
+	return func(ctx *gin.Context) {
+		if len(allowed) == 0 {
+			ctx.Next()
+			return
+		}
+
+		header := ctx.Writer.Header()
+		if !anyOrigin {
+			// The response depends on the origin, so caches must not serve
+			// it to other origins.
+			header.Add("Vary", "Origin")
+		}
+
+		origin := ctx.GetHeader("Origin")
+		if origin == "" {
+			ctx.Next()
+			return
+		}
+
+		preflight := ctx.Request.Method == http.MethodOptions && ctx.GetHeader("Access-Control-Request-Method") != ""
+
+		if !anyOrigin && !allowed[origin] {
+			if preflight {
+				app.errorResponse(ctx, http.StatusForbidden, "The origin is not allowed to call the API")
+				ctx.Abort()
+				return
+			}
+
+			ctx.Next()
+			return
+		}
+
+		if anyOrigin {
+			header.Set("Access-Control-Allow-Origin", config.AnyOrigin)
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cors.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			header.Set("Access-Control-Allow-Methods", corsAllowedMethods)
+			header.Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			header.Set("Access-Control-Max-Age", maxAge)
+			ctx.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		header.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+
+		ctx.Next()
+	}
+}
+
+// Content security policies. The API only returns JSON, which needs no
+// resources at all; the Swagger UI loads its scripts, styles and the API
+// description from this server and sets inline styles.
+const (
+	apiCSP     = "default-src 'none'; frame-ancestors 'none'"
+	swaggerCSP = "default-src 'self'; img-src 'self' data:; style-src 'self' 'unsafe-inline'; frame-ancestors 'none'; base-uri 'none'; form-action 'self'"
+)
+
+// SecurityHeadersMiddleware adds the headers that keep browsers from sniffing
+// content types, leaking URLs in the Referer header, framing the API and, if
+// configured, using plain HTTP.
+func (app *application) SecurityHeadersMiddleware() gin.HandlerFunc {
+	hsts := ""
+	if maxAge := app.config.Security.HSTSMaxAge; maxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", int(maxAge.Seconds()))
+	}
+
+	return func(ctx *gin.Context) {
+		header := ctx.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("Referrer-Policy", "no-referrer")
+		header.Set("Content-Security-Policy", apiCSP)
+		if hsts != "" {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+
+		ctx.Next()
+	}
+}
+
+// swaggerCSPMiddleware replaces the API's content security policy with one
+// that lets the Swagger UI run. If the API description is served from another
+// origin, the UI may fetch it from there.
+func (app *application) swaggerCSPMiddleware() gin.HandlerFunc {
+	policy := swaggerCSP
+	if u, err := url.Parse(app.config.Server.PublicURL); err == nil && u.Host != "" {
+		policy += "; connect-src 'self' " + u.Scheme + "://" + u.Host
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Header("Content-Security-Policy", policy)
+		ctx.Next()
+	}
+}
+
+// BodyLimitMiddleware rejects request bodies larger than limit bytes with 413
+// Content Too Large. A declared Content-Length is checked up front; other
+// bodies fail in bindingError once reading passes the limit.
+func (app *application) BodyLimitMiddleware(limit int64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.ContentLength > limit {
+			app.errorResponse(ctx, http.StatusRequestEntityTooLarge, bodyTooLargeMessage(limit))
+			ctx.Abort()
+			return
+		}
+
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+
+		ctx.Next()
+	}
+}
+
+func bodyTooLargeMessage(limit int64) string {
+	return fmt.Sprintf("The request body must not be larger than %d bytes", limit)
+}
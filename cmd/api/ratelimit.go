@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"rest-api-go-gin/internal/logger"
+	"rest-api-go-gin/internal/ratelimit"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitPolicy limits the requests to a route group. The name keeps the
+// buckets of different policies apart; key tells whose bucket a request
+// takes from, or returns "" if the policy does not apply to the request.
+type rateLimitPolicy struct {
+	name  string
+	limit ratelimit.Limit
+	key   func(*gin.Context) string
+}
+
+// perMinute returns a policy allowing requests per minute.
+func perMinute(name string, requests int, key func(*gin.Context) string) rateLimitPolicy {
+	return rateLimitPolicy{name: name, limit: ratelimit.Limit{Requests: requests, Per: time.Minute}, key: key}
+}
+
+// byClientIP limits each client IP. The IP comes from X-Forwarded-For only
+// behind a trusted proxy.
+func byClientIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// apiKeyHeader identifies the integration a request comes from.
+const apiKeyHeader = "X-API-Key"
+
+// byAPIKey limits each API key, stored hashed so that the keys do not end up
+// in the store. The keys are not verified, so a client could send a new one
+// with every request: the policy only applies on top of the limit per client
+// IP, never instead of it. Requests without a key are left to that limit.
+func byAPIKey(c *gin.Context) string {
+	key := c.GetHeader(apiKeyHeader)
+	if key == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	return "apikey:" + hex.EncodeToString(sum[:])
+}
+
+// byUser limits each authenticated user, wherever the requests come from. It
+// must run after AuthMiddleware.
+func (app *application) byUser(c *gin.Context) string {
+	user := app.GetUserFromContext(c)
+	if user.ID == 0 {
+		return byClientIP(c)
+	}
+
+	return "user:" + strconv.Itoa(user.ID)
+}
+
+// RateLimitMiddleware rejects requests beyond the policy's limit with 429 Too
+// Many Requests. Every response carries the RateLimit headers of the IETF
+// draft, rejected ones also Retry-After. If the store fails, requests are let
+// through: an outage of the limiter should not take the API down with it.
+func (app *application) RateLimitMiddleware(policy rateLimitPolicy) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := policy.key(ctx)
+		if app.rateLimits == nil || key == "" {
+			ctx.Next()
+			return
+		}
+
+		result, err := app.rateLimits.Take(ctx.Request.Context(), policy.name+":"+key, policy.limit, time.Now())
+		if err != nil {
+			logger.FromContext(ctx.Request.Context()).Error("failed to apply rate limit", "policy", policy.name, "error", err)
+			ctx.Next()
+			return
+		}
+
+		setRateLimitHeaders(ctx, policy, result)
+
+		if !result.Allowed {
+			app.metrics.RateLimited.WithLabelValues(policy.name).Inc()
+			ctx.Header("Retry-After", strconv.Itoa(max(1, ceilSeconds(result.RetryAfter))))
+			app.errorResponse(ctx, http.StatusTooManyRequests, "Too many requests, please try again later")
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// rateLimitState is what the rate limits applied so far to a request put in
+// its headers.
+type rateLimitState struct {
+	remaining int
+	policies  []string
+}
+
+const rateLimitStateKey = "rateLimit"
+
+// setRateLimitHeaders writes the RateLimit headers for a policy a request
+// passed through. Route groups stack policies: RateLimit-Policy lists all of
+// them, while RateLimit-Limit, -Remaining and -Reset describe the one with the
+// fewest requests left, which is the one the client runs into first.
+func setRateLimitHeaders(ctx *gin.Context, policy rateLimitPolicy, result ratelimit.Result) {
+	state, ok := ctx.Value(rateLimitStateKey).(*rateLimitState)
+	if !ok {
+		state = &rateLimitState{remaining: result.Remaining + 1}
+		ctx.Set(rateLimitStateKey, state)
+	}
+
+	if result.Remaining < state.remaining {
+		state.remaining = result.Remaining
+		ctx.Header("RateLimit-Limit", strconv.Itoa(policy.limit.Requests))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	}
+
+	state.policies = append(state.policies, fmt.Sprintf("%d;w=%d", policy.limit.Requests, ceilSeconds(policy.limit.Per)))
+	ctx.Header("RateLimit-Policy", strings.Join(state.policies, ", "))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// pruneRateLimits removes the buckets that have refilled, so that the store
+// does not grow with every client ever seen.
+func (app *application) pruneRateLimits(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := app.rateLimits.Prune(ctx, time.Now()); err != nil {
+			app.logger.Error("failed to prune rate limits", "error", err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rest-api-go-gin/internal/metrics"
+	"rest-api-go-gin/internal/ratelimit"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestByAPIKey(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/events", nil)
+
+	if key := byAPIKey(c); key != "" {
+		t.Errorf("byAPIKey() without a key = %q, want the policy not to apply", key)
+	}
+
+	c.Request.Header.Set(apiKeyHeader, "secret")
+	key := byAPIKey(c)
+	if !strings.HasPrefix(key, "apikey:") || strings.Contains(key, "secret") || len(key) != len("apikey:")+64 {
+		t.Errorf("byAPIKey() = %q, want apikey: and the SHA-256 of the key", key)
+	}
+}
+
+func TestAPIKeyRateLimit(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	gin.SetMode(gin.TestMode)
+	app := &application{metrics: metrics.New(db), rateLimits: ratelimit.NewMemoryStore()}
+
+	r := gin.New()
+	r.GET("/events",
+		app.RateLimitMiddleware(perMinute("public", 3, byClientIP)),
+		app.RateLimitMiddleware(perMinute("api-key", 2, byAPIKey)),
+		func(c *gin.Context) { c.Status(http.StatusNoContent) },
+	)
+
+	get := func(ip, key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/events", nil)
+		req.RemoteAddr = ip + ":1234"
+		if key != "" {
+			req.Header.Set(apiKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// One key is limited across client IPs.
+	for i, want := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+		ip := fmt.Sprintf("192.0.2.%d", i+1)
+		if got := get(ip, "shared"); got != want {
+			t.Errorf("request %d with the shared key from %s: status %d, want %d", i+1, ip, got, want)
+		}
+	}
+
+	// A new key for every request does not lift the limit per client IP.
+	for i, want := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+		key := fmt.Sprintf("rotated-%d", i+1)
+		if got := get("198.51.100.1", key); got != want {
+			t.Errorf("request %d with key %s: status %d, want %d", i+1, key, got, want)
+		}
+	}
+
+	// Without a key only the limit per client IP applies.
+	for i := range 3 {
+		if got := get("203.0.113.1", ""); got != http.StatusNoContent {
+			t.Errorf("request %d without a key: status %d, want %d", i+1, got, http.StatusNoContent)
+		}
+	}
+}
+
+func TestStackedRateLimitHeaders(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	gin.SetMode(gin.TestMode)
+	app := &application{metrics: metrics.New(db), rateLimits: ratelimit.NewMemoryStore()}
+
+	r := gin.New()
+	r.GET("/events",
+		app.RateLimitMiddleware(perMinute("public", 2, byClientIP)),
+		app.RateLimitMiddleware(perMinute("api-key", 5, byAPIKey)),
+		func(c *gin.Context) { c.Status(http.StatusNoContent) },
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set(apiKeyHeader, "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// The first policy has fewer requests left, so its values are kept.
+	want := map[string]string{
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "1",
+		"RateLimit-Policy":    "2;w=60, 5;w=60",
+	}
+	for header, value := range want {
+		if got := w.Header().Get(header); got != value {
+			t.Errorf("%s = %q, want %q", header, got, value)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"rest-api-go-gin/internal/database"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetEventHistory godoc
+// @Summary Get the revision history of an event
+// @Schemes
+// @Description List every recorded change to an event, newest first
+// @Tags Events
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Success 200 {array} database.Revision
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Security Bearer
+// @Router /events/{id}/history [get]
+func (app *application) getEventHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		app.errorResponse(c, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	user := app.GetUserFromContext(c) // Get current user from the context
+	existingEvent, err := app.models.Events.Get(c.Request.Context(), id)
+	if errors.Is(err, database.ErrNotFound) {
+		existingEvent, err = app.models.Events.GetDeleted(c.Request.Context(), id)
+	}
+	if err != nil {
+		app.modelError(c, err, "Failed to retrieve event")
+		return
+	}
+
+	if existingEvent.OwnerID != user.ID {
+		app.errorResponse(c, http.StatusForbidden, "You are not authorized to view the history of this event")
+		return
+	}
+
+	revisions, err := app.models.Revisions.GetByEvent(c.Request.Context(), id)
+	if err != nil {
+		app.modelError(c, err, "Failed to retrieve event history")
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// RevertEvent godoc
+// @Summary Revert an event to a previous revision
+// @Schemes
+// @Description Restore the name, description, date and location of an event from one of its revisions
+// @Tags Events
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Param revisionId path int true "Revision ID"
+// @Success 200 {object} database.Event
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Security Bearer
+// @Router /events/{id}/history/{revisionId}/revert [post]
+func (app *application) revertEvent(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		app.errorResponse(c, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	revisionID, err := strconv.Atoi(c.Param("revisionId"))
+	if err != nil {
+		app.errorResponse(c, http.StatusBadRequest, "Invalid revision ID")
+		return
+	}
+
+	user := app.GetUserFromContext(c) // Get current user from the context
+	ctx := c.Request.Context()
+
+	var revertedEvent *database.Event
+	err = app.models.WithTx(ctx, func(tx database.Models) error {
+		existingEvent, err := tx.Events.Get(ctx, id)
+		if err != nil {
+			return modelFailure(err, "Failed to retrieve event")
+		}
+
+		if existingEvent.OwnerID != user.ID {
+			return clientError(http.StatusForbidden, "You are not authorized to revert this event")
+		}
+
+		revertedEvent, err = tx.Events.Revert(ctx, id, revisionID, user.ID)
+		if err != nil {
+			return modelFailure(err, "Failed to revert event")
+		}
+		return nil
+	})
+	if err != nil {
+		app.txError(c, err, "Failed to revert event")
+		return
+	}
+
+	c.JSON(http.StatusOK, revertedEvent)
+}
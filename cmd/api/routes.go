@@ -1,20 +1,55 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func (app *application) routes() http.Handler {
-	g := gin.Default()
+	gin.DebugPrintFunc = func(format string, values ...any) {
+		app.logger.Debug(strings.TrimSpace(fmt.Sprintf(format, values...)))
+	}
+	gin.DebugPrintRouteFunc = func(method, path, handler string, handlers int) {
+		app.logger.Debug("route registered", "method", method, "path", path, "handler", handler)
+	}
+
+	g := gin.New()
+	// Only the configured proxies may set the client IP, which the rate
+	// limits are keyed by; gin trusts every proxy by default.
+	if err := g.SetTrustedProxies(app.config.Server.Proxies()); err != nil {
+		app.logger.Error("invalid trusted proxies", "error", err)
+	}
+	g.Use(otelgin.Middleware(serviceName), app.RequestIDMiddleware(), app.AccessLogMiddleware(), app.MetricsMiddleware(), app.RecoveryMiddleware())
+	g.Use(app.SecurityHeadersMiddleware(), app.CORSMiddleware())
+
+	g.NoRoute(func(c *gin.Context) {
+		app.errorResponse(c, http.StatusNotFound, "The requested resource could not be found")
+	})
 
 	v1 := g.Group("/api/v1")
+	v1.Use(app.BodyLimitMiddleware(int64(app.config.Server.MaxBodySize)))
+
+	// Rate limits of the route groups. Registering and logging in are limited
+	// tightly by client IP against credential stuffing and sign-up spam, the
+	// other public routes loosely, and by API key on top for clients that
+	// send one; authenticated routes are limited by user, and the event
+	// routes, which create and change events, more tightly on top.
+	limits := app.config.RateLimit
+	authLimit := app.RateLimitMiddleware(perMinute("auth", limits.Auth, byClientIP))
+	publicLimit := app.RateLimitMiddleware(perMinute("public", limits.Public, byClientIP))
+	apiKeyLimit := app.RateLimitMiddleware(perMinute("api-key", limits.APIKey, byAPIKey))
+	userLimit := app.RateLimitMiddleware(perMinute("user", limits.User, app.byUser))
+	eventsLimit := app.RateLimitMiddleware(perMinute("events", limits.Events, app.byUser))
 
 	// --- Public routes ---
 	auth := v1.Group("/auth")
+	auth.Use(authLimit)
 	{
 		auth.POST("/register", app.registerUser)
 		auth.POST("/login", app.login)
@@ -22,40 +57,100 @@ func (app *application) routes() http.Handler {
 
 	// Publicly accessible routes (if you want GET events public)
 	eventsPublic := v1.Group("/events")
+	eventsPublic.Use(publicLimit, apiKeyLimit)
 	{
 		eventsPublic.GET("", app.getAllEvents)
-		eventsPublic.GET("/:id", app.getEvent)
-		eventsPublic.GET("/:id/attendees", app.getAttendeesForEvent)
+		eventsPublic.GET("/facets", app.getEventFacets)
+		eventsPublic.GET("/:id", app.OptionalAuthMiddleware(), app.getEvent)
+		eventsPublic.GET("/:id/attendees", app.OptionalAuthMiddleware(), app.getAttendeesForEvent)
+	}
+
+	v1.GET("/categories", publicLimit, apiKeyLimit, app.getAllCategories)
+
+	venuesPublic := v1.Group("/venues")
+	venuesPublic.Use(publicLimit, apiKeyLimit)
+	{
+		venuesPublic.GET("", app.getAllVenues)
+		venuesPublic.GET("/:id", app.getVenue)
 	}
 
 	// --- Protected routes (require JWT) ---
 	authGroup := v1.Group("/")
-	authGroup.Use(app.AuthMiddleware())
+	authGroup.Use(app.AuthMiddleware(), userLimit)
 
 	// Protected event routes
 	events := authGroup.Group("/events")
+	events.Use(eventsLimit)
 	{
 		events.POST("", app.createEvent)
 		events.PUT("/:id", app.updateEvent)
 		events.DELETE("/:id", app.deleteEvent)
+		events.POST("/:id/restore", app.restoreEvent)
+
+		// lifecycle transitions
+		events.POST("/:id/publish", app.publishEvent)
+		events.POST("/:id/schedule", app.scheduleEvent)
+		events.POST("/:id/unschedule", app.unscheduleEvent)
+		events.POST("/:id/cancel", app.cancelEvent)
+		events.POST("/:id/complete", app.completeEvent)
+
+		events.GET("/:id/history", app.getEventHistory)
+		events.POST("/:id/history/:revisionId/revert", app.revertEvent)
 
 		// attendees under a specific event
 		events.POST("/:id/attendees/:userId", app.addAttendeeToEvent)
 		events.DELETE("/:id/attendees/:userId", app.deleteAttendeeFromEvent)
 	}
 
+	// Protected venue routes
+	venues := authGroup.Group("/venues")
+	{
+		venues.POST("", app.createVenue)
+		venues.PUT("/:id", app.updateVenue)
+	}
+
+	// Protected routes scoped to the current user
+	me := authGroup.Group("/me")
+	{
+		me.GET("/trash", app.getTrash)
+		me.DELETE("/trash/:id", app.purgeEvent)
+	}
+
 	// Protected attendee routes
 	attendees := authGroup.Group("/attendees")
 	{
 		attendees.GET("/:id/events", app.getEventsByAttendee)
 	}
 
-	g.GET("/swagger/*any", func(ctx *gin.Context) {
+	// Administration routes
+	admin := authGroup.Group("/admin")
+	admin.Use(app.AdminMiddleware())
+	{
+		admin.POST("/categories", app.createCategory)
+		admin.POST("/backups", app.createBackup)
+		admin.GET("/backups", app.getBackups)
+		admin.GET("/database/check", app.checkDatabase)
+	}
+
+	g.GET("/healthz", app.liveness)
+	g.GET("/readyz", app.readiness)
+	g.GET("/version", app.getVersion)
+
+	g.GET("/swagger/*any", app.swaggerCSPMiddleware(), func(ctx *gin.Context) {
 		if ctx.Request.RequestURI == "/swagger/" {
 			ctx.Redirect(302, "/swagger/index.html")
 		}
-		ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("http://localhost:8080/swagger/doc.json"))(ctx)
+		ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL(app.config.Server.PublicURL+"/swagger/doc.json"))(ctx)
 	})
 
 	return g
 }
+
+// metricsRoutes serves the Prometheus metrics. It is mounted on the admin port
+// only, so the metrics are not reachable through the public API.
+func (app *application) metricsRoutes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", app.metrics.Handler())
+
+	return mux
+}
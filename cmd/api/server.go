@@ -1,22 +1,128 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
+// serve runs the HTTP server, the metrics server on the admin port and the
+// background workers until the process receives SIGINT or SIGTERM or the
+// server fails. On a signal it first reports not ready for the drain period,
+// so that load balancers stop sending requests. Then, on every exit, it stops
+// accepting connections and waits for in-flight requests and workers to
+// finish within the configured shutdown timeout.
 func (app *application) serve() error {
 	server := &http.Server{
-		Addr: fmt.Sprintf(":%d", app.port),
-		Handler: app.routes(),
-		IdleTimeout: time.Minute,
-		ReadTimeout: 10 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		Addr:         fmt.Sprintf(":%d", app.config.Server.Port),
+		Handler:      app.routes(),
+		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+		IdleTimeout:  app.config.Server.IdleTimeout,
+		ReadTimeout:  app.config.Server.ReadTimeout,
+		WriteTimeout: app.config.Server.WriteTimeout,
 	}
 
-	log.Printf("Starting server on port %d", app.port)
+	metricsServer := &http.Server{
+		Addr:         fmt.Sprintf(":%d", app.config.Server.MetricsPort),
+		Handler:      app.metricsRoutes(),
+		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	app.background(func() { app.purgeExpiredTrash(ctx, time.Hour) })
+	app.background(func() { app.publishScheduledEvents(ctx, time.Minute) })
+	if app.rateLimits != nil {
+		app.background(func() { app.pruneRateLimits(ctx, 10*time.Minute) })
+	}
+	app.background(func() {
+		app.logger.Info("starting metrics server", "port", app.config.Server.MetricsPort)
+		if err := metricsServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			app.logger.Error("metrics server failed", "error", err)
+		}
+	})
+
+	serverErr := make(chan error, 1)
+
+	go func() {
+		app.logger.Info("starting server", "port", app.config.Server.Port)
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	app.ready.Store(true)
+
+	var err error
+	select {
+	case <-ctx.Done():
+		app.logger.Info("shutting down server", "drain", app.config.Server.ShutdownDrain)
+		app.ready.Store(false)
+		time.Sleep(app.config.Server.ShutdownDrain)
+	case err = <-serverErr:
+		app.logger.Error("server failed, shutting down", "error", err)
+		app.ready.Store(false)
+	}
+
+	// Stop the workers, which run until ctx is done.
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), app.config.Server.ShutdownTimeout)
+	defer cancel()
 
-	return server.ListenAndServe()
-}
\ No newline at end of file
+	err = errors.Join(err,
+		server.Shutdown(shutdownCtx),
+		metricsServer.Shutdown(shutdownCtx),
+		app.waitForBackground(shutdownCtx),
+	)
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("server stopped")
+
+	return nil
+}
+
+// background runs fn in a goroutine that shutdown waits for.
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Error("background task panicked", "error", err)
+			}
+		}()
+
+		fn()
+	}()
+}
+
+func (app *application) waitForBackground(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		app.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background tasks did not finish: %w", ctx.Err())
+	}
+}
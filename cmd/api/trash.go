@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"rest-api-go-gin/internal/database"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTrash godoc
+// @Summary Get trashed events
+// @Schemes
+// @Description Get the current user's deleted events that have not been purged yet
+// @Tags Trash
+// @Accept json
+// @Produce json
+// @Success 200 {array} database.Event
+// @Failure 401 {object} problem "Unauthorized"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Security Bearer
+// @Router /me/trash [get]
+func (app *application) getTrash(c *gin.Context) {
+	user := app.GetUserFromContext(c) // Get current user from the context
+
+	events, err := app.models.Events.GetTrashByOwner(c.Request.Context(), user.ID)
+	if err != nil {
+		app.modelError(c, err, "Failed to retrieve trash")
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// RestoreEvent godoc
+// @Summary Restore a deleted event
+// @Schemes
+// @Description Restore an event from the trash
+// @Tags Trash
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Success 200 {object} database.Event
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Security Bearer
+// @Router /events/{id}/restore [post]
+func (app *application) restoreEvent(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		app.errorResponse(c, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	user := app.GetUserFromContext(c) // Get current user from the context
+	ctx := c.Request.Context()
+
+	var trashedEvent *database.Event
+	err = app.models.WithTx(ctx, func(tx database.Models) error {
+		event, err := tx.Events.GetDeleted(ctx, id)
+		if err != nil {
+			return modelFailure(err, "Failed to retrieve event")
+		}
+		trashedEvent = event
+
+		if trashedEvent.OwnerID != user.ID {
+			return clientError(http.StatusForbidden, "You are not authorized to restore this event")
+		}
+
+		if err := tx.Events.Restore(ctx, id, user.ID); err != nil {
+			return modelFailure(err, "Failed to restore event")
+		}
+		return nil
+	})
+	if err != nil {
+		app.txError(c, err, "Failed to restore event")
+		return
+	}
+
+	trashedEvent.DeletedAt = nil
+	c.JSON(http.StatusOK, trashedEvent)
+}
+
+// PurgeEvent godoc
+// @Summary Permanently delete an event
+// @Schemes
+// @Description Permanently delete a trashed event and its attendees. This cannot be undone.
+// @Tags Trash
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Success 204 {object} nil "No Content"
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Security Bearer
+// @Router /me/trash/{id} [delete]
+func (app *application) purgeEvent(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		app.errorResponse(c, http.StatusBadRequest, "Invalid event ID")
+		return
+	}
+
+	user := app.GetUserFromContext(c) // Get current user from the context
+	ctx := c.Request.Context()
+	err = app.models.WithTx(ctx, func(tx database.Models) error {
+		trashedEvent, err := tx.Events.GetDeleted(ctx, id)
+		if err != nil {
+			return modelFailure(err, "Failed to retrieve event")
+		}
+
+		if trashedEvent.OwnerID != user.ID {
+			return clientError(http.StatusForbidden, "You are not authorized to purge this event")
+		}
+
+		if err := tx.Events.Purge(ctx, id, user.ID); err != nil {
+			return modelFailure(err, "Failed to purge event")
+		}
+		return nil
+	})
+	if err != nil {
+		app.txError(c, err, "Failed to purge event")
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// purgeExpiredTrash periodically removes events that have been in the trash
+// for longer than the configured retention period until ctx is cancelled.
+func (app *application) purgeExpiredTrash(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cutoff := time.Now().Add(-app.config.Trash.Retention)
+		purged, err := app.models.Events.PurgeDeletedBefore(ctx, cutoff)
+		if err != nil {
+			app.logger.Error("failed to purge expired trash", "error", err)
+		} else if purged > 0 {
+			app.logger.Info("purged expired trash", "events", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"rest-api-go-gin/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// setupValidation registers the translated validation messages and the
+// project's own validators with the validator gin binds requests with.
+func setupValidation() (*validation.Translations, error) {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil, fmt.Errorf("unexpected validator engine %T", binding.Validator.Engine())
+	}
+
+	return validation.Setup(v)
+}
+
+// bindingError writes the response for a request body that could not be
+// bound into value. Validation failures and values of the wrong JSON type list
+// every rejected field with a message in the language of the Accept-Language
+// header; malformed JSON is reported without exposing decoder internals.
+func (app *application) bindingError(c *gin.Context, err error, value any) {
+	var validationErrs validator.ValidationErrors
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &validationErrs):
+		trans := app.translations.Translator(c.GetHeader("Accept-Language"))
+		c.Header("Content-Language", trans.Locale())
+
+		fields := make([]fieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, fieldError{Field: fe.Field(), Message: app.translations.Message(trans, fe, value)})
+		}
+
+		app.writeProblem(c, problem{
+			Type:   problemValidation,
+			Status: http.StatusBadRequest,
+			Detail: "The request body has invalid fields",
+			Errors: fields,
+		})
+	case errors.As(err, &typeErr):
+		trans := app.translations.Translator(c.GetHeader("Accept-Language"))
+		c.Header("Content-Language", trans.Locale())
+
+		app.writeProblem(c, problem{
+			Type:   problemValidation,
+			Status: http.StatusBadRequest,
+			Detail: "The request body has invalid fields",
+			Errors: []fieldError{{Field: typeErr.Field, Message: app.translations.TypeMessage(trans, typeErr.Type)}},
+		})
+	case errors.As(err, &maxBytesErr):
+		app.errorResponse(c, http.StatusRequestEntityTooLarge, bodyTooLargeMessage(maxBytesErr.Limit))
+	case errors.Is(err, io.EOF):
+		app.errorResponse(c, http.StatusBadRequest, "The request body must not be empty")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		app.errorResponse(c, http.StatusBadRequest, "The request body is not valid JSON")
+	default:
+		app.errorResponse(c, http.StatusBadRequest, "The request body is invalid")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rest-api-go-gin/internal/validation"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+func TestBindingErrorLanguage(t *testing.T) {
+	translations, err := validation.Setup(validator.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	app := &application{translations: translations}
+
+	type request struct {
+		Name string `json:"name"`
+	}
+
+	r := gin.New()
+	r.POST("/", func(c *gin.Context) {
+		var body request
+		if err := c.ShouldBindJSON(&body); err != nil {
+			app.bindingError(c, err, &body)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": 42}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "ru-RU,ru;q=0.9")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Language") != "ru" {
+		t.Fatalf("status %d, Content-Language %q, want 400 in ru", w.Code, w.Header().Get("Content-Language"))
+	}
+
+	var p problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	want := fieldError{Field: "name", Message: "должно быть строкой"}
+	if len(p.Errors) != 1 || p.Errors[0] != want {
+		t.Errorf("errors = %+v, want %+v", p.Errors, want)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"rest-api-go-gin/internal/database"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetVenues godoc
+// @Summary Get all venues
+// @Schemes
+// @Description Get all venues
+// @Tags Venues
+// @Accept json
+// @Produce json
+// @Success 200 {array} database.Venue
+// @Failure 500 {object} problem "Internal Server Error"
+// @Router /venues [get]
+func (app *application) getAllVenues(c *gin.Context) {
+	venues, err := app.models.Venues.GetAll(c.Request.Context())
+	if err != nil {
+		app.modelError(c, err, "Failed to retrieve venues")
+		return
+	}
+
+	c.JSON(http.StatusOK, venues)
+}
+
+// GetVenue godoc
+// @Summary Get a venue By ID
+// @Schemes
+// @Description Get a venue By ID
+// @Tags Venues
+// @Accept json
+// @Produce json
+// @Param id path int true "Venue ID"
+// @Success 200 {object} database.Venue
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Router /venues/{id} [get]
+func (app *application) getVenue(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		app.errorResponse(c, http.StatusBadRequest, "Invalid venue ID")
+		return
+	}
+
+	venue, err := app.models.Venues.Get(c.Request.Context(), id)
+	if err != nil {
+		app.modelError(c, err, "Failed to retrieve venue")
+		return
+	}
+
+	c.JSON(http.StatusOK, venue)
+}
+
+// CreateVenue godoc
+// @Summary Create a venue
+// @Schemes
+// @Description Create new venue
+// @Tags Venues
+// @Accept json
+// @Produce json
+// @Param venue body database.Venue true "Venue object"
+// @Success 201 {object} database.Venue
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 401 {object} problem "Unauthorized"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Security Bearer
+// @Router /venues [post]
+func (app *application) createVenue(c *gin.Context) {
+	var venue database.Venue
+
+	if err := c.ShouldBindJSON(&venue); err != nil {
+		app.bindingError(c, err, &venue)
+		return
+	}
+
+	user := app.GetUserFromContext(c) // Get current user from the context
+	venue.OwnerID = &user.ID
+
+	if err := app.models.Venues.Insert(c.Request.Context(), &venue); err != nil {
+		app.modelError(c, err, "Failed to create venue")
+		return
+	}
+
+	c.JSON(http.StatusCreated, venue)
+}
+
+// UpdateVenue godoc
+// @Summary Update a venue
+// @Schemes
+// @Description Update an existing venue. Venues without an owner, such as those migrated from free-text locations, may only be updated by administrators. Updating a venue does not change its owner.
+// @Tags Venues
+// @Accept json
+// @Produce json
+// @Param id path int true "Venue ID"
+// @Param venue body database.Venue true "Venue object"
+// @Success 200 {object} database.Venue
+// @Failure 400 {object} problem "Bad Request"
+// @Failure 403 {object} problem "Forbidden"
+// @Failure 404 {object} problem "Not Found"
+// @Failure 500 {object} problem "Internal Server Error"
+// @Security Bearer
+// @Router /venues/{id} [put]
+func (app *application) updateVenue(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		app.errorResponse(c, http.StatusBadRequest, "Invalid venue ID")
+		return
+	}
+
+	user := app.GetUserFromContext(c) // Get current user from the context
+	updatedVenue := &database.Venue{}
+
+	if err := c.ShouldBindJSON(updatedVenue); err != nil {
+		app.bindingError(c, err, updatedVenue)
+		return
+	}
+
+	updatedVenue.ID = id
+
+	// Checking the owner and updating share a transaction, so that the venue
+	// cannot change hands in between.
+	ctx := c.Request.Context()
+	err = app.models.WithTx(ctx, func(tx database.Models) error {
+		existingVenue, err := tx.Venues.Get(ctx, id)
+		if err != nil {
+			return modelFailure(err, "Failed to retrieve venue")
+		}
+
+		// Venues without an owner are shared by the events of many users,
+		// which would move with it.
+		if existingVenue.OwnerID == nil && !user.IsAdmin {
+			return clientError(http.StatusForbidden, "Only administrators may update venues without an owner")
+		}
+		if existingVenue.OwnerID != nil && *existingVenue.OwnerID != user.ID {
+			return clientError(http.StatusForbidden, "You are not authorized to update this venue")
+		}
+		updatedVenue.OwnerID = existingVenue.OwnerID
+
+		if err := tx.Venues.Update(ctx, updatedVenue); err != nil {
+			return modelFailure(err, "Failed to update venue")
+		}
+		return nil
+	})
+	if err != nil {
+		app.txError(c, err, "Failed to update venue")
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedVenue)
+}
+
+// resolveVenue checks that the venue referenced by the event exists and uses
+// its name as the event location when none was given. It writes an error
+// response if the venue does not exist.
+func (app *application) resolveVenue(c *gin.Context, event *database.Event) bool {
+	if event.VenueID == nil {
+		return true
+	}
+
+	venue, err := app.models.Venues.Get(c.Request.Context(), *event.VenueID)
+	if errors.Is(err, database.ErrNotFound) {
+		app.errorResponse(c, http.StatusBadRequest, "Venue not found")
+		return false
+	}
+	if err != nil {
+		app.modelError(c, err, "Failed to retrieve venue")
+		return false
+	}
+
+	if event.Location == "" {
+		event.Location = venue.Name
+	}
+
+	return true
+}
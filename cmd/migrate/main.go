@@ -1,54 +1,348 @@
 package main
 
 import (
-	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
+	"rest-api-go-gin/internal/cli"
+	"rest-api-go-gin/internal/config"
+	"rest-api-go-gin/internal/database"
+	"rest-api-go-gin/internal/database/migrations"
+	"strconv"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/sqlite3"
-	"github.com/golang-migrate/migrate/v4/source/file"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
 )
 
+// migrationsRoot is where create adds migrations in the source tree. The
+// other commands use the migrations embedded in the binary unless -path is
+// given.
+const migrationsRoot = "internal/database/migrations"
+
+const usage = `Usage: migrate [flags] <command> [argument]
+
+Commands:
+  status       list the migrations and which of them are applied
+  version      print the current schema version
+  up [N]       apply all pending migrations, or the next N
+  down [N]     roll back the last N migrations, or all of them
+  goto V       migrate up or down to version V
+  force V      set the version to V and clear the dirty flag without running
+               any migration, after fixing a failed migration by hand
+  create NAME  add empty up and down files for a new migration
+
+Flags:
+`
+
+type options struct {
+	databaseURL string
+	path        string
+	yes         bool
+}
+
 func main() {
-	if len(os.Args) < 2 {
-		log.Fatal("Please provide a migration direction: 'up' or 'down'")
-	}
+	log.SetFlags(0)
 
-	direction := os.Args[1]
-	
-	db, err := sql.Open("sqlite3", "./data.db")
+	cfg, err := config.LoadEnv()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	defer db.Close()
+	var opts options
 
-	instance, err := sqlite3.WithInstance(db, &sqlite3.Config{})
-	if err != nil {
-		log.Fatal(err)
+	flag.StringVar(&opts.databaseURL, "database", cfg.Database.URL,
+		"database URL, or the path of a SQLite database file")
+	flag.StringVar(&opts.path, "path", "",
+		"migrations directory (default: the embedded migrations; create writes to "+migrationsRoot+"/<dialect>)")
+	flag.BoolVar(&opts.yes, "yes", false, "roll back without asking for confirmation")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
 	}
+	flag.Parse()
 
-	fSrc, err := (&file.File{}).Open("cmd/migrate/migrations")
-	if err != nil {
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	command, args := flag.Arg(0), flag.Args()[1:]
+
+	if err := run(opts, command, args); err != nil {
 		log.Fatal(err)
 	}
+}
 
-	m, err := migrate.NewWithInstance("file", fSrc, "sqlite3", instance)
+func run(opts options, command string, args []string) error {
+	if command == "create" {
+		if len(args) != 1 {
+			return errors.New("create needs the name of the migration")
+		}
+		return create(opts, args[0])
+	}
+
+	m, dialect, err := open(opts)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	defer m.Close()
 
-	switch direction {
+	switch command {
+	case "status":
+		return status(m, opts, dialect)
+	case "version":
+		version, dirty, err := m.Version()
+		if errors.Is(err, migrate.ErrNilVersion) {
+			fmt.Println("no migrations applied")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(versionString(version, dirty))
+		return nil
 	case "up":
-		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-			log.Fatal(err)
+		n, err := optionalCount(args)
+		if err != nil {
+			return err
 		}
+		if n == 0 {
+			return report(m.Up())
+		}
+		return report(m.Steps(n))
 	case "down":
-		if err := m.Down(); err != nil && err != migrate.ErrNoChange {
-			log.Fatal(err)
+		n, err := optionalCount(args)
+		if err != nil {
+			return err
+		}
+		what := "every migration"
+		if n > 0 {
+			what = fmt.Sprintf("the last %d migration(s)", n)
+		}
+		if !opts.yes && !cli.Confirm(fmt.Sprintf("Roll back %s of %s?", what, cli.Redact(opts.databaseURL))) {
+			return errors.New("aborted")
+		}
+		if n == 0 {
+			return report(m.Down())
+		}
+		return report(m.Steps(-n))
+	case "goto":
+		version, err := versionArgument(args)
+		if err != nil {
+			return err
+		}
+		if version < 0 {
+			return errors.New("goto needs a version of 0 or more")
+		}
+		return report(m.Migrate(uint(version)))
+	case "force":
+		version, err := versionArgument(args)
+		if err != nil {
+			return err
+		}
+		return m.Force(version)
+	default:
+		return fmt.Errorf("unknown command %q, run migrate -h for help", command)
+	}
+}
+
+// open connects to the database and its migrations.
+func open(opts options) (*migrate.Migrate, database.Dialect, error) {
+	dialect, dsn, err := cli.ParseDatabaseURL(opts.databaseURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	m, err := database.NewMigrator(dialect, dsn, opts.path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return m, dialect, nil
+}
+
+// migrationFS returns the migrations of the dialect.
+func migrationFS(opts options, dialect database.Dialect) (fs.FS, error) {
+	if opts.path != "" {
+		return os.DirFS(opts.path), nil
+	}
+
+	return fs.Sub(migrations.FS, string(dialect))
+}
+
+func status(m *migrate.Migrate, opts options, dialect database.Dialect) error {
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+	applied := err == nil
+
+	fsys, err := migrationFS(opts, dialect)
+	if err != nil {
+		return err
+	}
+
+	available, err := listMigrations(fsys)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("database: %s\n", cli.Redact(opts.databaseURL))
+	if applied {
+		fmt.Printf("version:  %s\n", versionString(version, dirty))
+	} else {
+		fmt.Println("version:  none")
+	}
+	fmt.Println()
+
+	for _, migration := range available {
+		state := "pending"
+		switch {
+		case applied && dirty && migration.version == version:
+			state = "dirty"
+		case applied && migration.version <= version:
+			state = "applied"
+		}
+		fmt.Printf("  %06d  %-8s %s\n", migration.version, state, migration.name)
+	}
+
+	return nil
+}
+
+// report prints the outcome of a migration run. Having nothing to do is not
+// an error.
+func report(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Println("no change")
+		return nil
+	}
+
+	return err
+}
+
+func versionString(version uint, dirty bool) string {
+	if dirty {
+		return fmt.Sprintf("%d (dirty)", version)
+	}
+
+	return strconv.FormatUint(uint64(version), 10)
+}
+
+func optionalCount(args []string) (int, error) {
+	switch len(args) {
+	case 0:
+		return 0, nil
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid number of migrations %q", args[0])
 		}
+		return n, nil
 	default:
-		log.Fatal("Invalid direction. Use 'up' or 'down'")
+		return 0, errors.New("too many arguments")
 	}
-}
\ No newline at end of file
+}
+
+func versionArgument(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, errors.New("a version is required")
+	}
+
+	version, err := strconv.Atoi(args[0])
+	if err != nil || version < -1 {
+		return 0, fmt.Errorf("invalid version %q", args[0])
+	}
+
+	return version, nil
+}
+
+type migrationFile struct {
+	version uint
+	name    string
+}
+
+var migrationName = regexp.MustCompile(`^(\d+)_(.+)\.up\.sql$`)
+
+// listMigrations returns the migrations in fsys in version order.
+func listMigrations(fsys fs.FS) ([]migrationFile, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := []migrationFile{}
+
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+
+		migrations = append(migrations, migrationFile{version: uint(version), name: match[2]})
+	}
+
+	return migrations, nil
+}
+
+var nonNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// create adds empty up and down files numbered after the newest migration.
+// Without -path the files are added to every dialect, which must stay at the
+// same versions.
+func create(opts options, name string) error {
+	name = strings.Trim(nonNameChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return errors.New("the migration name must contain letters or digits")
+	}
+
+	dirs := []string{opts.path}
+	if opts.path == "" {
+		dirs = []string{
+			filepath.Join(migrationsRoot, string(database.SQLite)),
+			filepath.Join(migrationsRoot, string(database.Postgres)),
+		}
+	}
+
+	var latest uint
+	for _, dir := range dirs {
+		existing, err := listMigrations(os.DirFS(dir))
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			latest = max(latest, existing[len(existing)-1].version)
+		}
+	}
+
+	base := fmt.Sprintf("%06d_%s", latest+1, name)
+
+	for _, dir := range dirs {
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(dir, base+"."+direction+".sql")
+
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+			if err != nil {
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+
+			fmt.Println("created", path)
+		}
+	}
+
+	return nil
+}
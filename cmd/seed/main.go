@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"rest-api-go-gin/internal/cli"
+	"rest-api-go-gin/internal/config"
+	"rest-api-go-gin/internal/database"
+	"rest-api-go-gin/internal/seed"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+const usage = `Usage: seed [flags]
+
+Fills the database with generated users, events and attendees, or with a
+fixture set. Generated users have the password ` + seed.DefaultPassword + `.
+
+Flags:
+`
+
+func main() {
+	log.SetFlags(0)
+
+	cfg, err := config.LoadEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	databaseURL := flag.String("database", cfg.Database.URL,
+		"database URL, or the path of a SQLite database file")
+	fixture := flag.String("fixture", "",
+		"load a fixture set instead of generating data: a .yaml, .yml or .json file or one of "+
+			strings.Join(seed.FixtureNames(), ", "))
+	users := flag.Int("users", 10, "number of users to generate")
+	events := flag.Int("events", 25, "number of events to generate")
+	attendees := flag.Int("attendees", 8, "maximum number of attendees per generated event")
+	randomSeed := flag.Uint64("seed", 1, "random seed; the same seed and start date generate the same data")
+	start := flag.String("start", time.Now().Format(time.DateOnly), "generated events take place in the 180 days after this date")
+	reset := flag.Bool("reset", false, "delete all data before seeding by rolling back and reapplying the migrations")
+	yes := flag.Bool("yes", false, "reset without asking for confirmation")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dialect, dsn, err := cli.ParseDatabaseURL(*databaseURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var data *seed.Fixture
+	if *fixture != "" {
+		data, err = seed.Load(*fixture)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		startDate, err := time.Parse(time.DateOnly, *start)
+		if err != nil {
+			log.Fatalf("invalid start date %q, use YYYY-MM-DD", *start)
+		}
+
+		data = seed.Generate(seed.Options{
+			Users:        *users,
+			Events:       *events,
+			MaxAttendees: *attendees,
+			Seed:         *randomSeed,
+			Start:        startDate,
+		})
+	}
+
+	if *reset {
+		if !*yes && !cli.Confirm(fmt.Sprintf("Delete all data in %s?", cli.Redact(*databaseURL))) {
+			log.Fatal("aborted")
+		}
+		if err := resetDatabase(dialect, dsn); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	openDSN := dsn
+	if dialect == database.SQLite {
+		// Enforce foreign keys so that fixtures cannot reference missing rows.
+		openDSN = database.SQLiteDSN(dsn, cfg.Database.SQLite.Pragmas(), false)
+	}
+
+	db, err := sql.Open(dialect.DriverName(), openDSN)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	if err := database.CheckSchemaVersion(ctx, db); err != nil {
+		log.Fatalf("%v; run the migrations first", err)
+	}
+
+	models := database.NewModels(db, database.Options{Dialect: dialect, Timeouts: cfg.Database.Timeouts()})
+
+	summary, err := data.Apply(ctx, models)
+	if summary != nil {
+		fmt.Printf("created %d users, %d events and %d attendees\n", summary.Users, summary.Events, summary.Attendees)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// resetDatabase empties the database by rolling back every migration and
+// applying them again, which also restores the default categories.
+func resetDatabase(dialect database.Dialect, dsn string) (err error) {
+	m, err := database.NewMigrator(dialect, dsn, "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		srcErr, dbErr := m.Close()
+		err = errors.Join(err, srcErr, dbErr)
+	}()
+
+	if err := m.Down(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("roll back migrations: %w", err)
+	}
+
+	if err := m.Up(); err != nil {
+		return fmt.Errorf("apply migrations: %w", err)
+	}
+
+	return nil
+}
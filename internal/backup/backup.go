@@ -0,0 +1,174 @@
+// Package backup takes consistent copies of the SQLite database while it is
+// in use, restores them and checks a database for corruption.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Options configures where backups go. Keep is how many backups Create
+// leaves in Dir, the newest ones; zero keeps them all.
+type Options struct {
+	Dir  string
+	Gzip bool
+	Keep int
+}
+
+// Backup is a backup file.
+type Backup struct {
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+const timeLayout = "20060102T150405.000Z"
+
+// backupName matches the files Create writes, and only those, so that
+// rotation never removes anything else.
+var backupName = regexp.MustCompile(`^backup-(\d{8}T\d{6}\.\d{3}Z)\.db(\.gz)?$`)
+
+// Create writes a backup of the SQLite database db into opts.Dir with VACUUM
+// INTO, which reads a consistent snapshot while other connections keep
+// writing. The file only appears under its final name once it is complete.
+// Afterwards the oldest backups beyond opts.Keep are removed.
+func Create(ctx context.Context, db *sql.DB, opts Options) (*Backup, error) {
+	if err := os.MkdirAll(opts.Dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	createdAt := time.Now().UTC()
+	path := filepath.Join(opts.Dir, "backup-"+createdAt.Format(timeLayout)+".db")
+
+	partial := path + ".partial"
+	if _, err := db.ExecContext(ctx, `VACUUM INTO $1`, partial); err != nil {
+		os.Remove(partial)
+		return nil, fmt.Errorf("vacuum into %s: %w", partial, err)
+	}
+
+	if opts.Gzip {
+		err := compress(partial, path+".gz.partial")
+		os.Remove(partial)
+		if err != nil {
+			os.Remove(path + ".gz.partial")
+			return nil, err
+		}
+
+		path += ".gz"
+		partial = path + ".partial"
+	}
+
+	if err := os.Rename(partial, path); err != nil {
+		os.Remove(partial)
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rotate(opts.Dir, opts.Keep); err != nil {
+		return nil, fmt.Errorf("rotate backups: %w", err)
+	}
+
+	return &Backup{Path: path, Size: info.Size(), CreatedAt: createdAt}, nil
+}
+
+// List returns the backups in dir, oldest first.
+func List(dir string) ([]*Backup, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*Backup{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []*Backup{}
+
+	for _, entry := range entries {
+		match := backupName.FindStringSubmatch(entry.Name())
+		if match == nil || !entry.Type().IsRegular() {
+			continue
+		}
+
+		createdAt, err := time.Parse(timeLayout, match[1])
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		backups = append(backups, &Backup{
+			Path:      filepath.Join(dir, entry.Name()),
+			Size:      info.Size(),
+			CreatedAt: createdAt,
+		})
+	}
+
+	slices.SortStableFunc(backups, func(a, b *Backup) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// rotate removes the oldest backups in dir until at most keep are left.
+func rotate(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+
+	backups, err := List(dir)
+	if err != nil {
+		return err
+	}
+
+	for len(backups) > keep {
+		if err := os.Remove(backups[0].Path); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+func compress(src, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, out.Close())
+	}()
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	return out.Sync()
+}
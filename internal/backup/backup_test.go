@@ -0,0 +1,237 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"rest-api-go-gin/internal/database"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openDB migrates a new SQLite database in a temporary directory and opens
+// it the way the API does.
+func openDB(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "data.db")
+	if _, err := database.MigrateUp(database.SQLite, path); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", database.SQLiteDSN(path, database.DefaultSQLitePragmas, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	database.SQLiteWriterPool.Apply(db)
+
+	return db, path
+}
+
+func countUsers(t *testing.T, path string) int {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+
+	return count
+}
+
+func insertUser(t *testing.T, db *sql.DB, email string) {
+	t.Helper()
+
+	if _, err := db.Exec(`INSERT INTO users (email, name, password) VALUES ($1, 'Test', 'x')`, email); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	ctx := context.Background()
+	db, path := openDB(t)
+	insertUser(t, db, "alice@example.com")
+
+	b, err := Create(ctx, db, Options{Dir: t.TempDir(), Gzip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(b.Path, ".db.gz") || b.Size == 0 {
+		t.Errorf("Create() = %+v, want a non-empty gzipped backup", b)
+	}
+
+	insertUser(t, db, "bob@example.com")
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := Restore(ctx, b.Path, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.SchemaVersion != database.SchemaVersion || !restored.Report.OK {
+		t.Errorf("Restore() = %+v, want schema version %d and a clean report", restored, database.SchemaVersion)
+	}
+
+	if got := countUsers(t, path); got != 1 {
+		t.Errorf("restored database has %d users, want 1", got)
+	}
+	if got := countUsers(t, restored.Previous); got != 2 {
+		t.Errorf("previous database %s has %d users, want 2", restored.Previous, got)
+	}
+}
+
+func TestRestoreKeepsUncheckpointedWrites(t *testing.T) {
+	ctx := context.Background()
+	db, path := openDB(t)
+
+	b, err := Create(ctx, db, Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Copy the database while its last write is only in the write-ahead
+	// log, as after a crash.
+	if _, err := db.Exec(`PRAGMA wal_autocheckpoint = 0`); err != nil {
+		t.Fatal(err)
+	}
+	insertUser(t, db, "alice@example.com")
+
+	crashed := filepath.Join(t.TempDir(), "data.db")
+	for _, suffix := range []string{"", "-wal"} {
+		data, err := os.ReadFile(path + suffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(crashed+suffix, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	restored, err := Restore(ctx, b.Path, crashed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := countUsers(t, crashed); got != 0 {
+		t.Errorf("restored database has %d users, want 0: the old write-ahead log was applied", got)
+	}
+	if got := countUsers(t, restored.Previous); got != 1 {
+		t.Errorf("previous database %s has %d users, want the 1 only in its write-ahead log", restored.Previous, got)
+	}
+}
+
+func TestRestoreRejectsNewerSchema(t *testing.T) {
+	ctx := context.Background()
+	db, path := openDB(t)
+	insertUser(t, db, "alice@example.com")
+
+	b, err := Create(ctx, db, Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newer, err := sql.Open("sqlite", b.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = newer.Exec(`UPDATE schema_migrations SET version = $1`, database.SchemaVersion+1)
+	newer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	insertUser(t, db, "bob@example.com")
+
+	if _, err := Restore(ctx, b.Path, path); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("Restore() error = %v, want the schema version to be rejected", err)
+	}
+
+	if got := countUsers(t, path); got != 2 {
+		t.Errorf("database has %d users after a rejected restore, want 2", got)
+	}
+	if _, err := os.Stat(path + ".restore"); !os.IsNotExist(err) {
+		t.Errorf("staged copy left behind: %v", err)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	ctx := context.Background()
+	db, _ := openDB(t)
+	dir := t.TempDir()
+
+	other := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(other, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var created []*Backup
+	for range 4 {
+		b, err := Create(ctx, db, Options{Dir: dir, Keep: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, b)
+
+		// Backups are named after the millisecond they are taken in.
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	backups, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 || backups[0].Path != created[2].Path || backups[1].Path != created[3].Path {
+		t.Errorf("List() after rotation = %v, want the two newest of %v", paths(backups), paths(created))
+	}
+
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("rotation removed a file that is not a backup: %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	db, _ := openDB(t)
+
+	report, err := Check(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK {
+		t.Errorf("Check() on a new database = %+v, want OK", report)
+	}
+
+	if _, err := db.Exec(`PRAGMA foreign_keys = OFF`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO attendees (event_id, user_id) VALUES (42, 42)`); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = Check(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK || len(report.ForeignKeys) == 0 || report.ForeignKeys[0].Table != "attendees" {
+		t.Errorf("Check() with a dangling attendee = %+v, want a foreign key violation", report)
+	}
+}
+
+func paths(backups []*Backup) []string {
+	var paths []string
+	for _, b := range backups {
+		paths = append(paths, filepath.Base(b.Path))
+	}
+
+	return paths
+}
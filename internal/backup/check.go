@@ -0,0 +1,70 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Report is the outcome of an integrity check.
+type Report struct {
+	OK bool `json:"ok"`
+	// Integrity lists the problems PRAGMA integrity_check found. It is empty
+	// when the database is intact.
+	Integrity []string `json:"integrity"`
+	// ForeignKeys lists the rows that reference a missing row.
+	ForeignKeys []ForeignKeyViolation `json:"foreignKeys"`
+}
+
+// ForeignKeyViolation is a row of PRAGMA foreign_key_check.
+type ForeignKeyViolation struct {
+	Table  string `json:"table"`
+	RowID  *int64 `json:"rowId"`
+	Parent string `json:"parent"`
+}
+
+// Check runs PRAGMA integrity_check and PRAGMA foreign_key_check on the
+// SQLite database db.
+func Check(ctx context.Context, db *sql.DB) (*Report, error) {
+	report := &Report{Integrity: []string{}, ForeignKeys: []ForeignKeyViolation{}}
+
+	rows, err := db.QueryContext(ctx, `PRAGMA integrity_check`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var message string
+		if err := rows.Scan(&message); err != nil {
+			return nil, err
+		}
+		if message != "ok" {
+			report.Integrity = append(report.Integrity, message)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	fkRows, err := db.QueryContext(ctx, `PRAGMA foreign_key_check`)
+	if err != nil {
+		return nil, err
+	}
+	defer fkRows.Close()
+
+	for fkRows.Next() {
+		var violation ForeignKeyViolation
+		var fkID int
+		if err := fkRows.Scan(&violation.Table, &violation.RowID, &violation.Parent, &fkID); err != nil {
+			return nil, err
+		}
+		report.ForeignKeys = append(report.ForeignKeys, violation)
+	}
+	if err := fkRows.Err(); err != nil {
+		return nil, err
+	}
+
+	report.OK = len(report.Integrity) == 0 && len(report.ForeignKeys) == 0
+
+	return report, nil
+}
@@ -0,0 +1,167 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"rest-api-go-gin/internal/database"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// Restored describes a restored database.
+type Restored struct {
+	SchemaVersion int
+	Report        *Report
+	// Previous is where the database that was replaced has been moved, if
+	// there was one.
+	Previous string
+}
+
+// Restore replaces the SQLite database at dst with the backup at src, which
+// may be gzipped. The backup is checked first: it must pass the integrity
+// check and its schema must be clean and not newer than this build expects.
+// An older schema is accepted and has to be migrated afterwards. The API must
+// not be running against dst.
+func Restore(ctx context.Context, src, dst string) (_ *Restored, err error) {
+	staged := dst + ".restore"
+	defer func() {
+		if err != nil {
+			os.Remove(staged)
+		}
+	}()
+
+	if err := stage(src, staged); err != nil {
+		return nil, err
+	}
+
+	restored, err := verify(ctx, staged)
+	if err != nil {
+		return nil, fmt.Errorf("backup %s: %w", src, err)
+	}
+
+	// The write-ahead log of the replaced database may hold writes that
+	// were never checkpointed into the file, and it would be applied to the
+	// restored one. It moves along with the file.
+	if _, err := os.Stat(dst); err == nil {
+		restored.Previous = dst + ".before-restore"
+		if err := moveDatabase(dst, restored.Previous); err != nil {
+			return nil, err
+		}
+	} else if err := removeJournal(dst); err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(staged, dst); err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// sqliteJournal are the suffixes of the files SQLite keeps next to a
+// database in WAL mode.
+var sqliteJournal = []string{"-wal", "-shm"}
+
+// moveDatabase renames the SQLite database at src to dst together with its
+// write-ahead log, replacing what is at dst.
+func moveDatabase(src, dst string) error {
+	if err := removeJournal(dst); err != nil {
+		return err
+	}
+
+	if err := os.Rename(src, dst); err != nil {
+		return err
+	}
+
+	for _, suffix := range sqliteJournal {
+		if err := os.Rename(src+suffix, dst+suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeJournal removes the write-ahead log of the SQLite database at path.
+func removeJournal(path string) error {
+	for _, suffix := range sqliteJournal {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stage copies the backup to path, decompressing it if needed.
+func stage(src, path string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var r io.Reader = in
+	if strings.HasSuffix(src, ".gz") {
+		zr, err := gzip.NewReader(in)
+		if err != nil {
+			return fmt.Errorf("backup %s: %w", src, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, out.Close())
+	}()
+
+	if _, err := io.Copy(out, r); err != nil {
+		return fmt.Errorf("backup %s: %w", src, err)
+	}
+
+	return out.Sync()
+}
+
+func verify(ctx context.Context, path string) (*Restored, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	report, err := Check(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Integrity) > 0 {
+		return nil, fmt.Errorf("integrity check failed: %s", strings.Join(report.Integrity, "; "))
+	}
+
+	version, dirty, err := database.CurrentSchemaVersion(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("read schema version: %w", err)
+	}
+	if dirty {
+		return nil, fmt.Errorf("schema version %d is dirty", version)
+	}
+	if version > database.SchemaVersion {
+		return nil, fmt.Errorf("schema version is %d, newer than %d expected by this build", version, database.SchemaVersion)
+	}
+
+	return &Restored{SchemaVersion: version, Report: report}, nil
+}
@@ -0,0 +1,41 @@
+// Package cli holds what the command line tools in cmd share.
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"rest-api-go-gin/internal/database"
+	"strings"
+)
+
+// ParseDatabaseURL is database.ParseURL that also accepts the path of a
+// SQLite database file, as the -database flags of the tools do.
+func ParseDatabaseURL(databaseURL string) (database.Dialect, string, error) {
+	if !strings.Contains(databaseURL, "://") {
+		databaseURL = "sqlite://" + databaseURL
+	}
+
+	return database.ParseURL(databaseURL)
+}
+
+// Redact hides the password of a database URL, for showing it.
+func Redact(databaseURL string) string {
+	u, err := url.Parse(databaseURL)
+	if err != nil || u.User == nil {
+		return databaseURL
+	}
+
+	return u.Redacted()
+}
+
+// Confirm asks a yes or no question on the terminal and defaults to no.
+func Confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
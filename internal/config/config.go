@@ -0,0 +1,351 @@
+// Package config holds the configuration of the API server. It is loaded from
+// defaults, a YAML or TOML file, environment variables and command line flags,
+// each overriding the ones before, and validated before the server starts.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"rest-api-go-gin/internal/database"
+	"rest-api-go-gin/internal/logger"
+	"rest-api-go-gin/internal/tracing"
+	"strings"
+	"time"
+)
+
+// Environments the server runs in. Development allows insecure settings that
+// production refuses, such as the default JWT secret.
+const (
+	Development = "development"
+	Production  = "production"
+)
+
+// DefaultJWTSecret is the JWT secret used when none is configured. It is
+// public, so it is only accepted in development.
+const DefaultJWTSecret = "some-secret-123456"
+
+// minJWTSecretLength is the shortest JWT secret accepted in production, the
+// size of the SHA-256 output used by HS256.
+const minJWTSecretLength = 32
+
+// Config is the configuration of the API server. The config tags name the
+// keys in the configuration file and, joined with dots, the command line
+// flags; env tags name the environment variables. Plain numbers given for a
+// duration are read in the unit of its unit tag, for compatibility with
+// variables such as SHUTDOWN_TIMEOUT_SECONDS.
+type Config struct {
+	Env       string    `config:"env" env:"APP_ENV" usage:"environment, development or production"`
+	Server    Server    `config:"server"`
+	Database  Database  `config:"database"`
+	Auth      Auth      `config:"auth"`
+	Log       Log       `config:"log"`
+	Tracing   Tracing   `config:"tracing"`
+	Health    Health    `config:"health"`
+	Trash     Trash     `config:"trash"`
+	Backup    Backup    `config:"backup"`
+	RateLimit RateLimit `config:"rate-limit"`
+	CORS      CORS      `config:"cors"`
+	Security  Security  `config:"security"`
+
+	// sources records where each value came from, by key.
+	sources map[string]string
+}
+
+type Server struct {
+	Port        int    `config:"port" env:"PORT" usage:"port of the API"`
+	MetricsPort int    `config:"metrics-port" env:"METRICS_PORT" usage:"port of the metrics server"`
+	PublicURL   string `config:"public-url" env:"PUBLIC_URL" usage:"URL clients reach the API at, if it differs from the request's host"`
+	// TrustedProxies may set X-Forwarded-For, which then gives the client IP
+	// used for logging and rate limiting.
+	TrustedProxies string `config:"trusted-proxies" env:"TRUSTED_PROXIES" usage:"comma-separated IPs or CIDR ranges of reverse proxies"`
+	MaxBodySize    int    `config:"max-body-size" env:"MAX_BODY_SIZE" usage:"largest request body accepted, in bytes"`
+
+	ReadTimeout     time.Duration `config:"read-timeout" env:"SERVER_READ_TIMEOUT" usage:"time allowed to read a request"`
+	WriteTimeout    time.Duration `config:"write-timeout" env:"SERVER_WRITE_TIMEOUT" usage:"time allowed to write a response"`
+	IdleTimeout     time.Duration `config:"idle-timeout" env:"SERVER_IDLE_TIMEOUT" usage:"time an idle keep-alive connection stays open"`
+	ShutdownTimeout time.Duration `config:"shutdown-timeout" env:"SHUTDOWN_TIMEOUT_SECONDS" unit:"1s" usage:"time allowed for in-flight requests to finish on shutdown"`
+	// ShutdownDrain gives load balancers time to see /readyz fail and stop
+	// sending requests before the server stops accepting connections.
+	ShutdownDrain time.Duration `config:"shutdown-drain" env:"SHUTDOWN_DRAIN_SECONDS" unit:"1s" usage:"time /readyz reports not ready before shutdown starts"`
+}
+
+type Database struct {
+	URL         string `config:"url" env:"DATABASE_URL" redact:"password" usage:"database URL, sqlite:// or postgres://"`
+	AutoMigrate bool   `config:"auto-migrate" env:"AUTO_MIGRATE" usage:"apply pending migrations at startup"`
+
+	MaxOpenConns    int           `config:"max-open-conns" env:"DB_MAX_OPEN_CONNS" usage:"maximum open connections of the read pool, 0 for no limit"`
+	MaxIdleConns    int           `config:"max-idle-conns" env:"DB_MAX_IDLE_CONNS" usage:"maximum idle connections of the read pool"`
+	ConnMaxLifetime time.Duration `config:"conn-max-lifetime" env:"DB_CONN_MAX_LIFETIME" usage:"maximum time a connection is reused"`
+	ConnMaxIdleTime time.Duration `config:"conn-max-idle-time" env:"DB_CONN_MAX_IDLE_TIME" usage:"maximum time a connection stays idle"`
+
+	ReadTimeout  time.Duration `config:"read-timeout" env:"DB_READ_TIMEOUT" usage:"time limit of queries, 0 for none"`
+	WriteTimeout time.Duration `config:"write-timeout" env:"DB_WRITE_TIMEOUT" usage:"time limit of writes, 0 for none"`
+	BatchTimeout time.Duration `config:"batch-timeout" env:"DB_BATCH_TIMEOUT" usage:"time limit of background jobs, 0 for none"`
+
+	SQLite SQLite `config:"sqlite"`
+}
+
+type SQLite struct {
+	JournalMode string        `config:"journal-mode" env:"SQLITE_JOURNAL_MODE" usage:"SQLite journal mode"`
+	BusyTimeout time.Duration `config:"busy-timeout" env:"SQLITE_BUSY_TIMEOUT" usage:"time SQLite waits for a lock"`
+	ForeignKeys bool          `config:"foreign-keys" env:"SQLITE_FOREIGN_KEYS" usage:"enforce foreign keys in SQLite"`
+	Synchronous string        `config:"synchronous" env:"SQLITE_SYNCHRONOUS" usage:"SQLite synchronous setting"`
+}
+
+type Auth struct {
+	JWTSecret string `config:"jwt-secret" env:"JWT_SECRET" redact:"all" usage:"secret that signs the access tokens"`
+}
+
+type Log struct {
+	Level  string `config:"level" env:"LOG_LEVEL" usage:"log level, debug, info, warn or error"`
+	Format string `config:"format" env:"LOG_FORMAT" usage:"log format, json or text"`
+}
+
+type Tracing struct {
+	Exporter string `config:"exporter" env:"OTEL_TRACES_EXPORTER" usage:"trace exporter, otlp, stdout or none"`
+}
+
+type Health struct {
+	CheckTimeout time.Duration `config:"check-timeout" env:"HEALTH_CHECK_TIMEOUT_SECONDS" unit:"1s" usage:"time limit of each readiness check"`
+}
+
+type Trash struct {
+	Retention time.Duration `config:"retention" env:"TRASH_RETENTION_DAYS" unit:"24h" usage:"time deleted events are kept before they are purged"`
+}
+
+type Backup struct {
+	Dir  string `config:"dir" env:"BACKUP_DIR" usage:"directory of the database backups"`
+	Gzip bool   `config:"gzip" env:"BACKUP_GZIP" usage:"compress backups with gzip"`
+	Keep int    `config:"keep" env:"BACKUP_KEEP" usage:"number of backups to keep, 0 for all"`
+}
+
+// RateLimit holds the limits of the route groups, in requests per minute.
+type RateLimit struct {
+	Enabled bool   `config:"enabled" env:"RATE_LIMIT_ENABLED" usage:"limit how often clients may call the API"`
+	Store   string `config:"store" env:"RATE_LIMIT_STORE" usage:"where the limits are kept, memory or database (PostgreSQL only)"`
+	Auth    int    `config:"auth" env:"RATE_LIMIT_AUTH" usage:"requests per minute and client IP to register and log in"`
+	Public  int    `config:"public" env:"RATE_LIMIT_PUBLIC" usage:"requests per minute and client IP to the public routes"`
+	APIKey  int    `config:"api-key" env:"RATE_LIMIT_API_KEY" usage:"requests per minute and X-API-Key to the public routes, on top of the limit per client IP"`
+	User    int    `config:"user" env:"RATE_LIMIT_USER" usage:"requests per minute and user to the authenticated routes"`
+	Events  int    `config:"events" env:"RATE_LIMIT_EVENTS" usage:"requests per minute and user to the authenticated event routes"`
+}
+
+// CORS is the policy for browsers calling the API from other origins. No
+// origin is allowed by default; each environment lists its frontends.
+type CORS struct {
+	AllowedOrigins   string        `config:"allowed-origins" env:"CORS_ALLOWED_ORIGINS" usage:"comma-separated origins allowed to call the API from a browser, * for any"`
+	AllowCredentials bool          `config:"allow-credentials" env:"CORS_ALLOW_CREDENTIALS" usage:"let browsers send credentials with cross-origin requests"`
+	MaxAge           time.Duration `config:"max-age" env:"CORS_MAX_AGE" usage:"time browsers may cache a preflight response"`
+}
+
+type Security struct {
+	HSTSMaxAge time.Duration `config:"hsts-max-age" env:"HSTS_MAX_AGE" usage:"time browsers must only use HTTPS for the API, 0 to not send HSTS"`
+}
+
+// AnyOrigin allows every origin in CORS.AllowedOrigins.
+const AnyOrigin = "*"
+
+// Rate limit stores. The database store needs PostgreSQL: on SQLite every
+// request would wait for the single write connection.
+const (
+	StoreMemory   = "memory"
+	StoreDatabase = "database"
+)
+
+// Default returns the configuration used for anything that is not set.
+func Default() Config {
+	return Config{
+		Env: Production,
+		Server: Server{
+			Port:            8080,
+			MetricsPort:     9090,
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     time.Minute,
+			ShutdownTimeout: 30 * time.Second,
+			ShutdownDrain:   5 * time.Second,
+			MaxBodySize:     1 << 20,
+		},
+		Database: Database{
+			URL:             "sqlite://./data.db",
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: time.Hour,
+			ConnMaxIdleTime: 15 * time.Minute,
+			ReadTimeout:     database.DefaultTimeouts.Read,
+			WriteTimeout:    database.DefaultTimeouts.Write,
+			BatchTimeout:    database.DefaultTimeouts.Batch,
+			SQLite: SQLite{
+				JournalMode: database.DefaultSQLitePragmas.JournalMode,
+				BusyTimeout: database.DefaultSQLitePragmas.BusyTimeout,
+				ForeignKeys: database.DefaultSQLitePragmas.ForeignKeys,
+				Synchronous: database.DefaultSQLitePragmas.Synchronous,
+			},
+		},
+		Auth:    Auth{JWTSecret: DefaultJWTSecret},
+		Log:     Log{Level: "info", Format: "json"},
+		Tracing: Tracing{Exporter: tracing.ExporterNone},
+		Health:  Health{CheckTimeout: 2 * time.Second},
+		Trash:   Trash{Retention: 30 * 24 * time.Hour},
+		Backup:  Backup{Dir: "./backups", Gzip: true, Keep: 7},
+		RateLimit: RateLimit{
+			Enabled: true,
+			Store:   StoreMemory,
+			Auth:    10,
+			Public:  300,
+			APIKey:  600,
+			User:    120,
+			Events:  30,
+		},
+		CORS:     CORS{MaxAge: 10 * time.Minute},
+		Security: Security{HSTSMaxAge: 365 * 24 * time.Hour},
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Env == Development || c.Env == Production, "env must be %s or %s, not %q", Development, Production, c.Env)
+
+	check(validPort(c.Server.Port), "server.port %d is not a valid port", c.Server.Port)
+	check(validPort(c.Server.MetricsPort), "server.metrics-port %d is not a valid port", c.Server.MetricsPort)
+	check(c.Server.Port != c.Server.MetricsPort, "server.port and server.metrics-port must differ")
+	if c.Server.PublicURL != "" {
+		u, err := url.Parse(c.Server.PublicURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"server.public-url must be an absolute http or https URL")
+	}
+	for _, proxy := range c.Server.Proxies() {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(net.ParseIP(proxy) != nil || cidrErr == nil, "server.trusted-proxies: %q is not an IP or CIDR range", proxy)
+	}
+	check(c.Server.MaxBodySize > 0, "server.max-body-size must be positive")
+	check(c.Server.ReadTimeout > 0, "server.read-timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write-timeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle-timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown-timeout must be positive")
+	check(c.Server.ShutdownDrain >= 0, "server.shutdown-drain must not be negative")
+
+	if _, _, err := database.ParseURL(c.Database.URL); err != nil {
+		errs = append(errs, fmt.Errorf("database.url: %w", err))
+	}
+	check(c.Database.MaxOpenConns >= 0, "database.max-open-conns must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.max-idle-conns must not be negative")
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn-max-lifetime must not be negative")
+	check(c.Database.ConnMaxIdleTime >= 0, "database.conn-max-idle-time must not be negative")
+	check(c.Database.ReadTimeout >= 0, "database.read-timeout must not be negative")
+	check(c.Database.WriteTimeout >= 0, "database.write-timeout must not be negative")
+	check(c.Database.BatchTimeout >= 0, "database.batch-timeout must not be negative")
+	check(c.Database.SQLite.BusyTimeout >= 0, "database.sqlite.busy-timeout must not be negative")
+
+	if c.Env != Development {
+		check(c.Auth.JWTSecret != DefaultJWTSecret,
+			"auth.jwt-secret is the public default; set JWT_SECRET, or APP_ENV=development for local use")
+		check(len(c.Auth.JWTSecret) >= minJWTSecretLength,
+			"auth.jwt-secret must be at least %d bytes long outside development", minJWTSecretLength)
+	}
+	check(c.Auth.JWTSecret != "", "auth.jwt-secret must not be empty")
+
+	if _, err := logger.New(io.Discard, c.Log.Level, c.Log.Format); err != nil {
+		errs = append(errs, fmt.Errorf("log: %w", err))
+	}
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterOTLP, tracing.ExporterStdout, tracing.ExporterNone:
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter must be %s, %s or %s, not %q",
+			tracing.ExporterOTLP, tracing.ExporterStdout, tracing.ExporterNone, c.Tracing.Exporter))
+	}
+
+	check(c.Health.CheckTimeout > 0, "health.check-timeout must be positive")
+	check(c.Trash.Retention > 0, "trash.retention must be positive")
+	check(c.Backup.Keep >= 0, "backup.keep must not be negative")
+
+	if c.RateLimit.Enabled {
+		check(c.RateLimit.Store == StoreMemory || c.RateLimit.Store == StoreDatabase,
+			"rate-limit.store must be %s or %s, not %q", StoreMemory, StoreDatabase, c.RateLimit.Store)
+		if dialect, _, err := database.ParseURL(c.Database.URL); err == nil {
+			check(c.RateLimit.Store != StoreDatabase || dialect == database.Postgres,
+				"rate-limit.store %s needs PostgreSQL; on SQLite every request would wait for the single write connection", StoreDatabase)
+		}
+		check(c.RateLimit.Auth > 0, "rate-limit.auth must be positive")
+		check(c.RateLimit.Public > 0, "rate-limit.public must be positive")
+		check(c.RateLimit.APIKey > 0, "rate-limit.api-key must be positive")
+		check(c.RateLimit.User > 0, "rate-limit.user must be positive")
+		check(c.RateLimit.Events > 0, "rate-limit.events must be positive")
+	}
+
+	for _, origin := range c.CORS.Origins() {
+		if origin == AnyOrigin {
+			check(!c.CORS.AllowCredentials, "cors.allowed-origins must list the origins when cors.allow-credentials is set")
+			continue
+		}
+		u, err := url.Parse(origin)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.Path == "" && u.RawQuery == "",
+			"cors.allowed-origins: %q is not an origin such as https://app.example.com", origin)
+	}
+	check(c.CORS.MaxAge >= 0, "cors.max-age must not be negative")
+	check(c.Security.HSTSMaxAge >= 0, "security.hsts-max-age must not be negative")
+
+	return errors.Join(errs...)
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+// Proxies returns the trusted proxies.
+func (s Server) Proxies() []string {
+	return splitList(s.TrustedProxies)
+}
+
+// Origins returns the allowed origins.
+func (c CORS) Origins() []string {
+	return splitList(c.AllowedOrigins)
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// Timeouts returns the time limits of the model methods.
+func (d Database) Timeouts() database.Timeouts {
+	return database.Timeouts{Read: d.ReadTimeout, Write: d.WriteTimeout, Batch: d.BatchTimeout}
+}
+
+// Pool returns the settings of the database connection pool.
+func (d Database) Pool() database.PoolOptions {
+	return database.PoolOptions{
+		MaxOpenConns:    d.MaxOpenConns,
+		MaxIdleConns:    d.MaxIdleConns,
+		ConnMaxLifetime: d.ConnMaxLifetime,
+		ConnMaxIdleTime: d.ConnMaxIdleTime,
+	}
+}
+
+// Pragmas returns the SQLite connection settings.
+func (s SQLite) Pragmas() database.SQLitePragmas {
+	return database.SQLitePragmas{
+		JournalMode: s.JournalMode,
+		BusyTimeout: s.BusyTimeout,
+		ForeignKeys: s.ForeignKeys,
+		Synchronous: s.Synchronous,
+	}
+}
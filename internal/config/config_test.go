@@ -0,0 +1,177 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// load loads the configuration with args and the file content, if any, named
+// name in a temporary directory.
+func load(t *testing.T, name, content string, args ...string) (*Config, error) {
+	t.Helper()
+
+	if content != "" {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		args = append([]string{"-config", path}, args...)
+	}
+
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	return Load(fs, args)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	t.Setenv("PORT", "7100")
+	t.Setenv("METRICS_PORT", "7101")
+
+	cfg, err := load(t, "config.yaml", `
+server:
+  port: 7000
+  metrics-port: 7001
+  public-url: https://api.example.com
+trash:
+  retention: 7
+`, "-server.port", "7200")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Server.Port != 7200 {
+		t.Errorf("port = %d, want the flag's 7200", cfg.Server.Port)
+	}
+	if cfg.Server.MetricsPort != 7101 {
+		t.Errorf("metrics port = %d, want the environment's 7101", cfg.Server.MetricsPort)
+	}
+	if cfg.Server.PublicURL != "https://api.example.com" {
+		t.Errorf("public URL = %q, want the file's", cfg.Server.PublicURL)
+	}
+	if cfg.Trash.Retention != 7*24*time.Hour {
+		t.Errorf("trash retention = %v, want 7 days", cfg.Trash.Retention)
+	}
+	if cfg.Backup.Keep != Default().Backup.Keep {
+		t.Errorf("backup keep = %d, want the default", cfg.Backup.Keep)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	cfg, err := load(t, "config.toml", `
+env = "development"
+
+[database]
+auto-migrate = true
+
+[database.sqlite]
+busy-timeout = "2s"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Env != Development || !cfg.Database.AutoMigrate || cfg.Database.SQLite.BusyTimeout != 2*time.Second {
+		t.Errorf("config = %+v", cfg)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := load(t, "config.yaml", "server:\n  prot: 7000\n"); err == nil || !strings.Contains(err.Error(), "server.prot") {
+		t.Errorf("unknown setting: err = %v", err)
+	}
+
+	t.Setenv("DB_READ_TIMEOUT", "3 seconds")
+	if _, err := load(t, "", ""); err == nil || !strings.Contains(err.Error(), "DB_READ_TIMEOUT") {
+		t.Errorf("invalid environment variable: err = %v", err)
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("backup:\n  dir: /var/backups/api\n  keep: 3\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("BACKUP_KEEP", "5")
+
+	cfg, err := LoadEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Backup.Dir != "/var/backups/api" || cfg.Backup.Keep != 5 || cfg.Backup.Gzip != Default().Backup.Gzip {
+		t.Errorf("backup = %+v, want the file's directory and the environment's keep", cfg.Backup)
+	}
+
+	t.Setenv("BACKUP_GZIP", "sometimes")
+	if _, err := LoadEnv(); err == nil || !strings.Contains(err.Error(), "BACKUP_GZIP") {
+		t.Errorf("invalid environment variable: err = %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "jwt-secret") {
+		t.Errorf("default JWT secret in production: err = %v", err)
+	}
+
+	cfg.Env = Development
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("default configuration in development: err = %v", err)
+	}
+
+	cfg.Server.MetricsPort = cfg.Server.Port
+	cfg.Log.Level = "loud"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "metrics-port") || !strings.Contains(err.Error(), "loud") {
+		t.Errorf("invalid settings: err = %v, want both reported", err)
+	}
+
+	cfg = Default()
+	cfg.Env = Development
+	cfg.CORS.AllowedOrigins = "https://app.example.com, *"
+	cfg.CORS.AllowCredentials = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "allow-credentials") {
+		t.Errorf("any origin with credentials: err = %v", err)
+	}
+
+	cfg.CORS.AllowedOrigins = "https://app.example.com/"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "is not an origin") {
+		t.Errorf("origin with a path: err = %v", err)
+	}
+
+	cfg = Default()
+	cfg.Env = Development
+	cfg.RateLimit.Store = StoreDatabase
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "rate-limit.store") {
+		t.Errorf("database rate limit store on SQLite: err = %v", err)
+	}
+	cfg.Database.URL = "postgres://api@localhost/api"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("database rate limit store on PostgreSQL: err = %v", err)
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	t.Setenv("JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("DATABASE_URL", "postgres://app:hunter2@db/app")
+
+	cfg, err := load(t, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := cfg.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(out.String(), "0123456789abcdef") || strings.Contains(out.String(), "hunter2") {
+		t.Errorf("printed configuration contains a secret:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "env DATABASE_URL") {
+		t.Errorf("printed configuration does not show where values came from:\n%s", out.String())
+	}
+}
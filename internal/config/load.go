@@ -0,0 +1,295 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Load registers the -config flag and a flag for every setting on fs, parses
+// args with it and returns the configuration. Settings come from Default, the
+// file named by -config or CONFIG_FILE, the environment and the flags, each
+// overriding the ones before. The result is not validated.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+	cfg.sources = map[string]string{}
+
+	fields := fieldsOf(&cfg)
+
+	path := fs.String("config", os.Getenv("CONFIG_FILE"), "configuration file, YAML or TOML")
+
+	flagValues := map[string]string{}
+	for _, f := range fields {
+		fs.Var(&flagValue{field: f, values: flagValues}, f.key, f.usage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.loadFileAndEnv(fields, *path); err != nil {
+		return nil, err
+	}
+
+	for _, f := range fields {
+		if value, ok := flagValues[f.key]; ok {
+			if err := cfg.set(f, value, "flag -"+f.key); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &cfg, nil
+}
+
+// LoadEnv returns the configuration from Default, the file named by
+// CONFIG_FILE and the environment, without the flags of Load. It is for the
+// command line tools, which have flags of their own. The result is not
+// validated.
+func LoadEnv() (*Config, error) {
+	cfg := Default()
+	cfg.sources = map[string]string{}
+
+	if err := cfg.loadFileAndEnv(fieldsOf(&cfg), os.Getenv("CONFIG_FILE")); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// loadFileAndEnv applies the settings in the file at path, if any, and then
+// those in the environment.
+func (c *Config) loadFileAndEnv(fields []field, path string) error {
+	if path != "" {
+		if err := c.loadFile(fields, path); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if value, ok := os.LookupEnv(f.env); ok {
+			if err := c.set(f, value, "env "+f.env); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadFile applies the settings in the YAML or TOML file at path.
+func (c *Config) loadFile(fields []field, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var settings map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &settings)
+	case ".toml":
+		err = toml.Unmarshal(data, &settings)
+	default:
+		return fmt.Errorf("%s: unsupported configuration format %q, expected .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	byKey := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byKey[f.key] = f
+	}
+
+	values := map[string]string{}
+	if err := flatten(settings, "", values); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for key, value := range values {
+		f, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("%s: unknown setting %q", path, key)
+		}
+		if err := c.set(f, value, "file "+path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flatten turns nested settings into values keyed by their dotted path.
+func flatten(settings map[string]any, prefix string, values map[string]string) error {
+	for name, value := range settings {
+		key := prefix + name
+
+		switch v := value.(type) {
+		case map[string]any:
+			if err := flatten(v, key+".", values); err != nil {
+				return err
+			}
+		case []any:
+			return fmt.Errorf("setting %q cannot be a list", key)
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) set(f field, value, source string) error {
+	if err := f.set(value); err != nil {
+		return fmt.Errorf("%s: invalid value %q for %s: %w", source, value, f.key, err)
+	}
+	c.sources[f.key] = source
+
+	return nil
+}
+
+// field is a single setting of a Config.
+type field struct {
+	key    string
+	env    string
+	usage  string
+	unit   time.Duration
+	redact string
+	value  reflect.Value
+}
+
+// fieldsOf returns the settings of cfg in declaration order.
+func fieldsOf(cfg *Config) []field {
+	var fields []field
+	collectFields(reflect.ValueOf(cfg).Elem(), "", &fields)
+
+	return fields
+}
+
+func collectFields(v reflect.Value, prefix string, fields *[]field) {
+	t := v.Type()
+
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		name, ok := sf.Tag.Lookup("config")
+		if !ok {
+			continue
+		}
+
+		if sf.Type.Kind() == reflect.Struct {
+			collectFields(v.Field(i), prefix+name+".", fields)
+			continue
+		}
+
+		f := field{
+			key:    prefix + name,
+			env:    sf.Tag.Get("env"),
+			usage:  sf.Tag.Get("usage"),
+			redact: sf.Tag.Get("redact"),
+			value:  v.Field(i),
+		}
+		if unit := sf.Tag.Get("unit"); unit != "" {
+			f.unit, _ = time.ParseDuration(unit)
+		}
+		if f.env != "" {
+			f.usage += " (env " + f.env + ")"
+		}
+
+		*fields = append(*fields, f)
+	}
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// set parses value into the field.
+func (f field) set(value string) error {
+	switch {
+	case f.value.Type() == durationType:
+		if f.unit > 0 {
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				f.value.SetInt(n * int64(f.unit))
+				return nil
+			}
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(int64(d))
+	case f.value.Kind() == reflect.String:
+		f.value.SetString(value)
+	case f.value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(int64(n))
+	case f.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		f.value.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %s", f.value.Type())
+	}
+
+	return nil
+}
+
+// String formats the value of the field, with secrets redacted.
+func (f field) String() string {
+	value := fmt.Sprint(f.value.Interface())
+
+	switch {
+	case value == "":
+		return value
+	case f.redact == "all":
+		return "[redacted]"
+	case f.redact == "password":
+		if u, err := url.Parse(value); err == nil {
+			return u.Redacted()
+		}
+	}
+
+	return value
+}
+
+// flagValue collects the value of a setting's flag, to be applied once the
+// file and the environment have been read.
+type flagValue struct {
+	field  field
+	values map[string]string
+}
+
+func (v *flagValue) String() string {
+	if v.field.value.IsValid() {
+		return v.field.String()
+	}
+
+	return ""
+}
+
+func (v *flagValue) Set(value string) error {
+	v.values[v.field.key] = value
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	return v.field.value.IsValid() && v.field.value.Kind() == reflect.Bool
+}
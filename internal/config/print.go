@@ -0,0 +1,23 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Print writes every setting with its effective value and where the value
+// came from. Secrets and the password in the database URL are redacted.
+func (c *Config) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	for _, f := range fieldsOf(c) {
+		source, ok := c.sources[f.key]
+		if !ok {
+			source = "default"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.key, f, source)
+	}
+
+	return tw.Flush()
+}
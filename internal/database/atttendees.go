@@ -3,12 +3,10 @@ package database
 import (
 	"context"
 	"database/sql"
-	"log"
-	"time"
 )
 
 type AttendeeModel struct {
-	DB *sql.DB
+	model
 }
 
 type Attendee struct {
@@ -17,13 +15,19 @@ type Attendee struct {
 	EventID int `json:"eventId"`
 }
 
-func (a *AttendeeModel) Insert(attendee *Attendee) (*Attendee, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+func (a *AttendeeModel) Insert(ctx context.Context, attendee *Attendee, actorID int) (_ *Attendee, err error) {
+	ctx, done := a.instrument(ctx, "attendee", "Insert", a.timeouts.Write)
+	defer done(&err)
+
+	tx, err := a.db.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
 	query := `INSERT INTO attendees (event_id, user_id) VALUES ($1, $2) RETURNING id`
 
-	err := a.DB.QueryRowContext(
+	err = tx.QueryRowContext(
 		ctx,
 		query,
 		attendee.EventID,
@@ -34,17 +38,29 @@ func (a *AttendeeModel) Insert(attendee *Attendee) (*Attendee, error) {
 		return nil, err
 	}
 
+	err = recordAttendeeRevision(ctx, tx, attendee.EventID, actorID, RevisionAttendeeAdded, FieldChange{
+		From: nil,
+		To:   attendee.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	return attendee, nil
 }
 
-func (a *AttendeeModel) GetByEventAndAttendee(eventID, userID int) (*Attendee, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+func (a *AttendeeModel) GetByEventAndAttendee(ctx context.Context, eventID, userID int) (_ *Attendee, err error) {
+	ctx, done := a.instrument(ctx, "attendee", "GetByEventAndAttendee", a.timeouts.Read)
+	defer done(&err)
 
 	query := `SELECT id, event_id, user_id FROM attendees WHERE event_id = $1 AND user_id = $2`
 
 	var attendee Attendee
-	err := a.DB.QueryRowContext(
+	err = a.readDB.QueryRowContext(
 		ctx,
 		query,
 		eventID,
@@ -57,7 +73,7 @@ func (a *AttendeeModel) GetByEventAndAttendee(eventID, userID int) (*Attendee, e
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, nil
+			return nil, notFound("attendee")
 		}
 		return nil, err
 	}
@@ -65,18 +81,19 @@ func (a *AttendeeModel) GetByEventAndAttendee(eventID, userID int) (*Attendee, e
 	return &attendee, nil
 }
 
-func (a *AttendeeModel) GetAttendeesByEvent(eventID int) ([]*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+func (a *AttendeeModel) GetAttendeesByEvent(ctx context.Context, eventID int) (_ []*User, err error) {
+	ctx, done := a.instrument(ctx, "attendee", "GetAttendeesByEvent", a.timeouts.Read)
+	defer done(&err)
 
 	query := `
 		SELECT u.id, u.name, u.email
 		FROM users u
 		JOIN attendees a ON u.id = a.user_id
-		WHERE a.event_id = $1
+		JOIN events e ON e.id = a.event_id
+		WHERE a.event_id = $1 AND e.deleted_at IS NULL
 	`
 
-	rows, err := a.DB.QueryContext(ctx, query, eventID)
+	rows, err := a.readDB.QueryContext(ctx, query, eventID)
 	if err != nil {
 		return nil, err
 	}
@@ -98,13 +115,19 @@ func (a *AttendeeModel) GetAttendeesByEvent(eventID int) ([]*User, error) {
 	return attendees, nil
 }
 
-func (a *AttendeeModel) Delete(eventID, userID int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+func (a *AttendeeModel) Delete(ctx context.Context, eventID, userID, actorID int) (err error) {
+	ctx, done := a.instrument(ctx, "attendee", "Delete", a.timeouts.Write)
+	defer done(&err)
+
+	tx, err := a.db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
 	query := `DELETE FROM attendees WHERE event_id = $1 AND user_id = $2`
 
-	res, err := a.DB.ExecContext(ctx, query, userID)
+	res, err := tx.ExecContext(ctx, query, eventID, userID)
 	if err != nil {
 		return err
 	}
@@ -115,8 +138,33 @@ func (a *AttendeeModel) Delete(eventID, userID int) error {
 	}
 
 	if rowsAffected == 0 {
-		log.Println("Attendee removal failed. No rows exist in db!!")
+		return notFound("attendee")
+	}
+
+	err = recordAttendeeRevision(ctx, tx, eventID, actorID, RevisionAttendeeRemoved, FieldChange{
+		From: userID,
+		To:   nil,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// recordAttendeeRevision records a change to the attendee list of an event.
+// The event itself is unchanged, so its current state is used as the snapshot.
+func recordAttendeeRevision(ctx context.Context, tx querier, eventID, actorID int, action string, change FieldChange) error {
+	event, err := getEventTx(ctx, tx, eventID)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return insertRevision(ctx, tx, &Revision{
+		EventID:  eventID,
+		ActorID:  actorID,
+		Action:   action,
+		Changes:  map[string]FieldChange{"attendee": change},
+		Snapshot: event,
+	})
 }
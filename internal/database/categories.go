@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+type CategoryModel struct {
+	model
+}
+
+type Category struct {
+	ID   int    `json:"id"`
+	Name string `json:"name" binding:"required,notblank,min=2,max=50"`
+	Slug string `json:"slug"`
+}
+
+// ErrEmptySlug is returned when a category name has no ASCII letters or digits
+// to build its slug from.
+var ErrEmptySlug = errors.New("category name has no letters or digits for a slug")
+
+func (m *CategoryModel) Insert(ctx context.Context, category *Category) (err error) {
+	ctx, done := m.instrument(ctx, "category", "Insert", m.timeouts.Write)
+	defer done(&err)
+
+	category.Name = strings.TrimSpace(category.Name)
+	category.Slug = slugify(category.Name)
+	if category.Slug == "" {
+		return ErrEmptySlug
+	}
+
+	query := `INSERT INTO categories (name, slug) VALUES ($1, $2) RETURNING id`
+
+	return m.db.QueryRowContext(ctx, query, category.Name, category.Slug).Scan(&category.ID)
+}
+
+func (m *CategoryModel) GetAll(ctx context.Context) (_ []*Category, err error) {
+	ctx, done := m.instrument(ctx, "category", "GetAll", m.timeouts.Read)
+	defer done(&err)
+
+	rows, err := m.readDB.QueryContext(ctx, `SELECT id, name, slug FROM categories ORDER BY LOWER(name), name`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	categories := []*Category{}
+
+	for rows.Next() {
+		var category Category
+		if err := rows.Scan(&category.ID, &category.Name, &category.Slug); err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, &category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func (m *CategoryModel) Get(ctx context.Context, id int) (_ *Category, err error) {
+	ctx, done := m.instrument(ctx, "category", "Get", m.timeouts.Read)
+	defer done(&err)
+
+	query := `SELECT id, name, slug FROM categories WHERE id = $1`
+
+	var category Category
+	err = m.readDB.QueryRowContext(ctx, query, id).Scan(&category.ID, &category.Name, &category.Slug)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("category")
+		}
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify turns a category name into a lowercase, URL friendly identifier.
+func slugify(name string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
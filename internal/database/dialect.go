@@ -0,0 +1,73 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Dialect is the flavour of SQL spoken by the database behind the models.
+// The queries are written to run on every dialect; the few places where the
+// dialects differ ask the dialect.
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// ParseURL reads a DATABASE_URL and returns its dialect and the data source
+// name to open it with. PostgreSQL URLs start with postgres:// or
+// postgresql:// and are passed to the driver as they are. SQLite URLs are a
+// file path after sqlite://, such as sqlite://./data.db, optionally followed
+// by driver parameters, such as sqlite://./data.db?_pragma=cache_size(-20000).
+func ParseURL(databaseURL string) (Dialect, string, error) {
+	scheme, rest, ok := strings.Cut(databaseURL, "://")
+	if !ok {
+		return "", "", fmt.Errorf("database URL %q has no scheme", databaseURL)
+	}
+
+	switch scheme {
+	case "postgres", "postgresql":
+		return Postgres, databaseURL, nil
+	case "sqlite", "sqlite3":
+		file, query, _ := strings.Cut(rest, "?")
+		if file == "" {
+			return "", "", fmt.Errorf("database URL %q has no file path", databaseURL)
+		}
+		if _, err := url.ParseQuery(query); err != nil {
+			return "", "", fmt.Errorf("database URL %q: %w", databaseURL, err)
+		}
+		return SQLite, rest, nil
+	default:
+		return "", "", fmt.Errorf("unsupported database URL scheme %q", scheme)
+	}
+}
+
+// DriverName returns the name of the database/sql driver for the dialect.
+// The program must import the driver package itself.
+func (d Dialect) DriverName() string {
+	if d == Postgres {
+		return "pgx"
+	}
+
+	return "sqlite"
+}
+
+// timestamp returns t as a query argument that compares correctly with the
+// timestamps the dialect stores. SQLite keeps timestamps as text in the
+// format of CURRENT_TIMESTAMP, so t is formatted the same way; PostgreSQL
+// compares real timestamps, truncated to whole seconds so that both dialects
+// return what they were given.
+func (d Dialect) timestamp(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+
+	if d == Postgres {
+		return t.UTC().Truncate(time.Second)
+	}
+
+	return t.UTC().Format(time.DateTime)
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Model methods report failures the API is expected to handle with the errors
+// below, wrapped with details. Any other error is unexpected.
+var (
+	// ErrNotFound is returned when the requested row does not exist.
+	ErrNotFound = errors.New("database: row not found")
+	// ErrConflict is returned when a write would duplicate a unique value.
+	ErrConflict = errors.New("database: conflicting row exists")
+	// ErrForeignKey is returned when a write references a row that does not
+	// exist.
+	ErrForeignKey = errors.New("database: referenced row does not exist")
+	// ErrTimeout is returned when a model method runs longer than its
+	// configured timeout or the database stays locked by another writer.
+	ErrTimeout = errors.New("database: operation timed out")
+	// ErrCanceled is returned when the caller gave up on a model method, for
+	// example because the client disconnected.
+	ErrCanceled = errors.New("database: operation canceled")
+)
+
+// NotFoundError is the ErrNotFound of a model method. Entity names the kind of
+// row that does not exist, such as "event"; errors.Is matches it with
+// ErrNotFound.
+type NotFoundError struct {
+	Entity string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Entity + " not found"
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// notFound returns ErrNotFound for the named entity.
+func notFound(entity string) error {
+	return &NotFoundError{Entity: entity}
+}
+
+// Timeouts bounds how long model methods may run, by kind of operation. Batch
+// covers the background jobs that touch many rows at once. A zero timeout
+// means no limit.
+type Timeouts struct {
+	Read  time.Duration
+	Write time.Duration
+	Batch time.Duration
+}
+
+var DefaultTimeouts = Timeouts{
+	Read:  3 * time.Second,
+	Write: 3 * time.Second,
+	Batch: 30 * time.Second,
+}
+
+// translateError turns driver and context errors into the errors above and
+// returns other errors unchanged.
+func translateError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if ctxErr := contextError(ctx, err); ctxErr != err {
+		return ctxErr
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return translatePgError(pgErr, err)
+	}
+
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return fmt.Errorf("%w: %w", ErrForeignKey, err)
+	}
+
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+
+	return err
+}
+
+// translatePgError maps PostgreSQL error codes to the errors above. Lock
+// timeouts, serialization failures and deadlocks are reported as ErrTimeout,
+// like a busy SQLite database.
+func translatePgError(pgErr *pgconn.PgError, err error) error {
+	switch pgErr.Code {
+	case "23505": // unique_violation
+		return fmt.Errorf("%w: %w", ErrConflict, err)
+	case "23503": // foreign_key_violation
+		return fmt.Errorf("%w: %w", ErrForeignKey, err)
+	case "55P03", "40001", "40P01": // lock_not_available, serialization_failure, deadlock_detected
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	default:
+		return err
+	}
+}
+
+// retryable reports whether err means that the database was busy or that the
+// transaction could not be serialized with a concurrent one, so that running
+// it again may succeed.
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01" // serialization_failure, deadlock_detected
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() & 0xff {
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+			return true
+		}
+	}
+
+	return false
+}
+
+// contextError wraps err in ErrTimeout or ErrCanceled if it was caused by ctx
+// and returns other errors unchanged.
+func contextError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	case errors.Is(err, context.Canceled), errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("%w: %w", ErrCanceled, err)
+	default:
+		return err
+	}
+}
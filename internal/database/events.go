@@ -7,27 +7,52 @@ import (
 )
 
 type EventModel struct {
-	DB *sql.DB
+	model
 }
 
 type Event struct {
-	ID          int    `json:"id"`
-	OwnerID     int    `json:"ownerId"`
-	Name        string `json:"name" binding:"required,min=3"`
-	Description string `json:"description" binding:"required,min=10"`
-	Date        string `json:"date" binding:"required,datetime=2006-01-02"`
-	Location    string `json:"location" binding:"required,min=3"`
+	ID           int        `json:"id"`
+	OwnerID      int        `json:"ownerId"`
+	Name         string     `json:"name" binding:"required,notblank,min=3"`
+	Description  string     `json:"description" binding:"required,notblank,min=10"`
+	Date         string     `json:"date" binding:"required,datetime=2006-01-02"`
+	Location     string     `json:"location" binding:"required_without=VenueID,omitempty,min=3"`
+	VenueID      *int       `json:"venueId,omitempty"`
+	CategoryID   *int       `json:"categoryId,omitempty"`
+	Tags         []string   `json:"tags" binding:"omitempty,max=20,dive,max=50"`
+	Status       string     `json:"status"`
+	PublishAt    *time.Time `json:"publishAt,omitempty"`
+	CancelReason *string    `json:"cancelReason,omitempty"`
+	DeletedAt    *time.Time `json:"deletedAt,omitempty"`
+
+	// DistanceKm is only set when events are searched by location.
+	DistanceKm *float64 `json:"distanceKm,omitempty" binding:"-"`
 }
 
-func (m *EventModel) Insert(event *Event) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+const eventColumns = `
+	e.id, e.owner_id, e.name, e.description, e.date, e.location, e.venue_id, e.category_id,
+	e.status, e.publish_at, e.cancel_reason, e.deleted_at
+`
+
+func (m *EventModel) Insert(ctx context.Context, event *Event) (err error) {
+	ctx, done := m.instrument(ctx, "event", "Insert", m.timeouts.Write)
+	defer done(&err)
+
+	tx, err := m.db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if event.Status == "" {
+		event.Status = EventStatusDraft
+	}
 
 	query := `
-		INSERT INTO events (owner_id, name, description, date, location) 
-		VALUES ($1, $2, $3, $4, $5) RETURNING id
+		INSERT INTO events (owner_id, name, description, date, location, venue_id, category_id, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id
 	`
-	return m.DB.QueryRowContext(
+	err = tx.QueryRowContext(
 		ctx,
 		query,
 		event.OwnerID,
@@ -35,147 +60,453 @@ func (m *EventModel) Insert(event *Event) error {
 		event.Description,
 		event.Date,
 		event.Location,
+		event.VenueID,
+		event.CategoryID,
+		event.Status,
 	).Scan(
 		&event.ID,
 	)
+	if err != nil {
+		return err
+	}
+
+	if err := setEventTags(ctx, tx, event.ID, event.Tags); err != nil {
+		return err
+	}
+	event.Tags = NormalizeTags(event.Tags)
+
+	if err := recordRevision(ctx, tx, event.ID, event.OwnerID, RevisionCreated, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (m *EventModel) GetAll() ([]*Event, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+// GetAll returns every publicly listed event that matches the filter. Drafts
+// and events scheduled for later publication are left out. When the filter
+// searches near a point the events are sorted by distance, nearest first.
+func (m *EventModel) GetAll(ctx context.Context, filter EventFilter) (_ []*Event, err error) {
+	ctx, done := m.instrument(ctx, "event", "GetAll", m.timeouts.Read)
+	defer done(&err)
 
-	query := `SELECT * from events`
+	return m.getAll(ctx, filter)
+}
 
-	rows, err := m.DB.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
+func (m *EventModel) getAll(ctx context.Context, filter EventFilter) ([]*Event, error) {
+	where, args := filter.where()
+
+	query := `SELECT ` + eventColumns + ` FROM events e WHERE ` + where
+
+	events, err := m.queryEvents(ctx, query, args...)
+	if err != nil || filter.Near == nil {
+		return events, err
 	}
 
-	defer rows.Close()
+	return filterByDistance(ctx, m.readDB, events, *filter.Near, filter.RadiusKm)
+}
 
-	events := []*Event{}
+// Get returns the event with the given id, or ErrNotFound if it does not
+// exist or has been moved to the trash.
+func (m *EventModel) Get(ctx context.Context, id int) (_ *Event, err error) {
+	ctx, done := m.instrument(ctx, "event", "Get", m.timeouts.Read)
+	defer done(&err)
 
-	for rows.Next() {
-		var event Event
-		err := rows.Scan(
-			&event.ID,
-			&event.OwnerID,
-			&event.Name,
-			&event.Description,
-			&event.Date,
-			&event.Location,
-		)
-		if err != nil {
-			return nil, err
-		}
+	query := `SELECT ` + eventColumns + ` FROM events e WHERE e.id = $1 AND e.deleted_at IS NULL`
 
-		events = append(events, &event)
-	}
+	return m.getEvent(ctx, query, id)
+}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
+// GetDeleted returns the trashed event with the given id, or ErrNotFound if
+// there is no such event in the trash.
+func (m *EventModel) GetDeleted(ctx context.Context, id int) (_ *Event, err error) {
+	ctx, done := m.instrument(ctx, "event", "GetDeleted", m.timeouts.Read)
+	defer done(&err)
 
-	return events, nil
+	query := `SELECT ` + eventColumns + ` FROM events e WHERE e.id = $1 AND e.deleted_at IS NOT NULL`
+
+	return m.getEvent(ctx, query, id)
 }
 
-func (m *EventModel) Get(id int) (*Event, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+func (m *EventModel) Update(ctx context.Context, event *Event, actorID int) (err error) {
+	ctx, done := m.instrument(ctx, "event", "Update", m.timeouts.Write)
+	defer done(&err)
 
-	query := `SELECT * FROM events WHERE id=$1`
+	return m.update(ctx, event, actorID, RevisionUpdated)
+}
 
-	var event Event
+// Revert restores the editable fields of an event to the state recorded in
+// one of its revisions. The revert itself is recorded as a new revision. It
+// returns ErrNotFound if the revision does not belong to the event.
+func (m *EventModel) Revert(ctx context.Context, eventID, revisionID, actorID int) (_ *Event, err error) {
+	ctx, done := m.instrument(ctx, "event", "Revert", m.timeouts.Write)
+	defer done(&err)
 
-	err := m.DB.QueryRowContext(
-		ctx,
-		query,
-		id,
-	).Scan(
-		&event.ID,
-		&event.OwnerID,
-		&event.Name,
-		&event.Description,
-		&event.Date,
-		&event.Location,
-	)
+	revision, err := getRevision(ctx, m.readDB, revisionID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
+	if revision.EventID != eventID || revision.Snapshot == nil {
+		return nil, notFound("revision")
+	}
 
-	return &event, nil
+	reverted := *revision.Snapshot
+	reverted.ID = eventID
+
+	if err := m.update(ctx, &reverted, actorID, RevisionReverted); err != nil {
+		return nil, err
+	}
+
+	return m.getEvent(ctx, `SELECT `+eventColumns+` FROM events e WHERE e.id = $1`, eventID)
 }
 
-func (m *EventModel) Update(event *Event) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+func (m *EventModel) update(ctx context.Context, event *Event, actorID int, action string) error {
+	tx, err := m.db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getEventTx(ctx, tx, event.ID)
+	if err != nil {
+		return err
+	}
 
-	query := `UPDATE events SET name = $1, description = $2, date = $3, location = $4 WHERE id = $5`
+	query := `
+		UPDATE events SET name = $1, description = $2, date = $3, location = $4, venue_id = $5,
+			category_id = $6
+		WHERE id = $7 AND deleted_at IS NULL
+	`
 
-	_, err := m.DB.ExecContext(
+	res, err := tx.ExecContext(
 		ctx,
 		query,
 		event.Name,
 		event.Description,
 		event.Date,
 		event.Location,
+		event.VenueID,
+		event.CategoryID,
 		event.ID,
 	)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return notFound("event")
+	}
+
+	if err := setEventTags(ctx, tx, event.ID, event.Tags); err != nil {
+		return err
+	}
+	event.Tags = NormalizeTags(event.Tags)
+
+	if err := recordRevision(ctx, tx, event.ID, actorID, action, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (m *EventModel) Delete(id int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+// Delete moves the event to the trash. The row and its attendees are kept
+// until the event is restored or purged.
+func (m *EventModel) Delete(ctx context.Context, id, actorID int) (err error) {
+	ctx, done := m.instrument(ctx, "event", "Delete", m.timeouts.Write)
+	defer done(&err)
 
-	query := `DELETE FROM events WHERE id = $1`
+	query := `UPDATE events SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL`
 
-	_, err := m.DB.ExecContext(
-		ctx,
-		query,
-		id,
-	)
+	return m.changeWithRevision(ctx, id, actorID, RevisionDeleted, query)
+}
+
+// Restore takes the event out of the trash.
+func (m *EventModel) Restore(ctx context.Context, id, actorID int) (err error) {
+	ctx, done := m.instrument(ctx, "event", "Restore", m.timeouts.Write)
+	defer done(&err)
+
+	query := `UPDATE events SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	return m.changeWithRevision(ctx, id, actorID, RevisionRestored, query)
+}
+
+// Purge permanently removes a trashed event together with its attendees.
+// Its revision history is kept.
+func (m *EventModel) Purge(ctx context.Context, id, actorID int) (err error) {
+	ctx, done := m.instrument(ctx, "event", "Purge", m.timeouts.Write)
+	defer done(&err)
+
+	tx, err := m.db.begin(ctx)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	if err := purgeEvent(ctx, tx, id, actorID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PurgeDeletedBefore permanently removes every event that was moved to the
+// trash before the cutoff and returns how many events were removed.
+func (m *EventModel) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (_ int64, err error) {
+	ctx, done := m.instrument(ctx, "event", "PurgeDeletedBefore", m.timeouts.Batch)
+	defer done(&err)
+
+	before := m.dialect.timestamp(&cutoff)
+
+	tx, err := m.db.begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM events WHERE deleted_at IS NOT NULL AND deleted_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := purgeEvent(ctx, tx, id, SystemActorID); err != nil {
+			return 0, err
+		}
+	}
 
-	return nil
+	return int64(len(ids)), tx.Commit()
 }
 
-func (m *EventModel) GetByAttendee(attendeeID int) ([]*Event, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+// GetTrashByOwner returns the owner's trashed events, most recently deleted first.
+func (m *EventModel) GetTrashByOwner(ctx context.Context, ownerID int) (_ []*Event, err error) {
+	ctx, done := m.instrument(ctx, "event", "GetTrashByOwner", m.timeouts.Read)
+	defer done(&err)
 
 	query := `
-		SELECT e.id, e.name, e.description, e.date, e.location FROM events e
+		SELECT ` + eventColumns + ` FROM events e
+		WHERE e.owner_id = $1 AND e.deleted_at IS NOT NULL
+		ORDER BY e.deleted_at DESC
+	`
+
+	return m.queryEvents(ctx, query, ownerID)
+}
+
+func (m *EventModel) GetByAttendee(ctx context.Context, attendeeID int, viewer *User) (_ []*Event, err error) {
+	ctx, done := m.instrument(ctx, "event", "GetByAttendee", m.timeouts.Read)
+	defer done(&err)
+
+	args := []any{attendeeID}
+	visible := ` AND ` + publicCondition
+	switch {
+	case viewer != nil && viewer.IsAdmin:
+		visible = ``
+	case viewer != nil:
+		args = append(args, viewer.ID)
+		visible = ` AND (` + publicCondition + ` OR e.owner_id = $2)`
+	}
+
+	query := `
+		SELECT ` + eventColumns + ` FROM events e
 		INNER JOIN attendees a ON a.event_id = e.id
-		WHERE a.user_id = $1
+		WHERE a.user_id = $1 AND e.deleted_at IS NULL` + visible + `
 		ORDER BY e.date DESC
 	`
-	rows, err := m.DB.QueryContext(ctx, query, attendeeID)
+
+	return m.queryEvents(ctx, query, args...)
+}
+
+// changeWithRevision runs a single-row statement against the event and
+// records the resulting change as a revision.
+func (m *EventModel) changeWithRevision(ctx context.Context, id, actorID int, action, query string) error {
+	tx, err := m.db.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getEventTx(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return notFound("event")
+	}
+
+	if err := recordRevision(ctx, tx, id, actorID, action, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func purgeEvent(ctx context.Context, tx querier, id, actorID int) error {
+	before, err := getEventTx(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM attendees WHERE event_id = $1`, id); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM events WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return notFound("event")
+	}
+
+	return insertRevision(ctx, tx, &Revision{
+		EventID:  id,
+		ActorID:  actorID,
+		Action:   RevisionPurged,
+		Snapshot: before,
+	})
+}
+
+// recordRevision writes a revision describing how the event changed from
+// before to its current state within tx.
+func recordRevision(ctx context.Context, tx querier, eventID, actorID int, action string, before *Event) error {
+	after, err := getEventTx(ctx, tx, eventID)
+	if err != nil {
+		return err
+	}
+
+	changes, err := diffEvents(before, after)
+	if err != nil {
+		return err
+	}
+
+	return insertRevision(ctx, tx, &Revision{
+		EventID:  eventID,
+		ActorID:  actorID,
+		Action:   action,
+		Changes:  changes,
+		Snapshot: after,
+	})
+}
+
+// getEventTx loads an event within tx, including events in the trash.
+func getEventTx(ctx context.Context, tx querier, id int) (*Event, error) {
+	var event Event
+
+	query := `SELECT ` + eventColumns + ` FROM events e WHERE e.id = $1`
+
+	if err := scanEvent(tx.QueryRowContext(ctx, query, id), &event); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("event")
+		}
+		return nil, err
+	}
+
+	if err := loadEventTags(ctx, tx, &event); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+func (m *EventModel) getEvent(ctx context.Context, query string, args ...any) (*Event, error) {
+	var event Event
+
+	err := scanEvent(m.readDB.QueryRowContext(ctx, query, args...), &event)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("event")
+		}
+		return nil, err
+	}
+
+	if err := loadEventTags(ctx, m.readDB, &event); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+func (m *EventModel) queryEvents(ctx context.Context, query string, args ...any) ([]*Event, error) {
+	rows, err := m.readDB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
 
-	var events []*Event
+	events := []*Event{}
+
 	for rows.Next() {
 		var event Event
-		err = rows.Scan(&event.ID, &event.Name, &event.Description, &event.Date, &event.Location)
-		if err != nil {
+		if err := scanEvent(rows, &event); err != nil {
 			return nil, err
 		}
 
 		events = append(events, &event)
 	}
 
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := loadEventTags(ctx, m.readDB, events...); err != nil {
+		return nil, err
+	}
+
 	return events, nil
 }
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// querier is the subset of *sql.DB and *sql.Tx used by helpers that run both
+// inside and outside of a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func scanEvent(row rowScanner, event *Event) error {
+	return row.Scan(
+		&event.ID,
+		&event.OwnerID,
+		&event.Name,
+		&event.Description,
+		&event.Date,
+		&event.Location,
+		&event.VenueID,
+		&event.CategoryID,
+		&event.Status,
+		&event.PublishAt,
+		&event.CancelReason,
+		&event.DeletedAt,
+	)
+}
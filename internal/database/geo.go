@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const earthRadiusKm = 6371.0
+
+// GeoPoint is a position in decimal degrees.
+type GeoPoint struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// DistanceKm returns the great-circle distance between two points using the
+// haversine formula.
+func DistanceKm(a, b GeoPoint) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := (b.Latitude - a.Latitude) * math.Pi / 180
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// boundingBoxCondition returns a condition on venues v that keeps every venue
+// within radiusKm of center, and some outside of it. It lets the database use the
+// coordinates index before the exact distance is computed.
+func boundingBoxCondition(center GeoPoint, radiusKm float64, args []any) (string, []any) {
+	latDelta := radiusKm / (earthRadiusKm * math.Pi / 180)
+	minLat := math.Max(-90, center.Latitude-latDelta)
+	maxLat := math.Min(90, center.Latitude+latDelta)
+
+	args = append(args, minLat, maxLat)
+	condition := fmt.Sprintf(`v.latitude BETWEEN $%d AND $%d`, len(args)-1, len(args))
+
+	// Near the poles or across the antimeridian the longitude range wraps,
+	// so only the latitude band is used to narrow the search.
+	cosLat := math.Cos(center.Latitude * math.Pi / 180)
+	if cosLat < 1e-6 {
+		return condition, args
+	}
+
+	lngDelta := latDelta / cosLat
+	minLng := center.Longitude - lngDelta
+	maxLng := center.Longitude + lngDelta
+	if minLng < -180 || maxLng > 180 {
+		return condition, args
+	}
+
+	args = append(args, minLng, maxLng)
+	condition += fmt.Sprintf(` AND v.longitude BETWEEN $%d AND $%d`, len(args)-1, len(args))
+
+	return condition, args
+}
+
+// filterByDistance sets the distance of every event from center, drops the
+// events further away than radiusKm and sorts the rest nearest first.
+func filterByDistance(ctx context.Context, q querier, events []*Event, center GeoPoint, radiusKm float64) ([]*Event, error) {
+	if len(events) == 0 {
+		return events, nil
+	}
+
+	// Look up the venues in the bounding box rather than those of the events,
+	// so that the query does not grow with the number of events.
+	box, args := boundingBoxCondition(center, radiusKm, nil)
+	query := `
+		SELECT v.id, v.latitude, v.longitude FROM venues v
+		WHERE v.latitude IS NOT NULL AND v.longitude IS NOT NULL AND ` + box
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	distances := map[int]float64{}
+
+	for rows.Next() {
+		var id int
+		var point GeoPoint
+		if err := rows.Scan(&id, &point.Latitude, &point.Longitude); err != nil {
+			return nil, err
+		}
+
+		distances[id] = DistanceKm(center, point)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	nearby := []*Event{}
+
+	for _, event := range events {
+		if event.VenueID == nil {
+			continue
+		}
+
+		distance, ok := distances[*event.VenueID]
+		if !ok || distance > radiusKm {
+			continue
+		}
+
+		event.DistanceKm = &distance
+		nearby = append(nearby, event)
+	}
+
+	sort.SliceStable(nearby, func(i, j int) bool {
+		return *nearby[i].DistanceKm < *nearby[j].DistanceKm
+	})
+
+	return nearby, nil
+}
@@ -0,0 +1,183 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+const (
+	EventStatusDraft     = "draft"
+	EventStatusScheduled = "scheduled"
+	EventStatusPublished = "published"
+	EventStatusCancelled = "cancelled"
+	EventStatusCompleted = "completed"
+)
+
+const (
+	RevisionScheduled   = "schedule"
+	RevisionUnscheduled = "unschedule"
+	RevisionPublished   = "publish"
+	RevisionCancelled   = "cancel"
+	RevisionCompleted   = "complete"
+)
+
+var ErrInvalidTransition = errors.New("invalid event status transition")
+
+// publicStatuses are the statuses of events everyone may see. Drafts and
+// scheduled events are only visible to their owner.
+var publicStatuses = []string{EventStatusPublished, EventStatusCancelled, EventStatusCompleted}
+
+// publicCondition is the SQL condition on the events table e that matches
+// the public statuses.
+const publicCondition = `e.status IN ('published', 'cancelled', 'completed')`
+
+// Public reports whether everyone may see the event.
+func (e *Event) Public() bool {
+	return slices.Contains(publicStatuses, e.Status)
+}
+
+// VisibleTo reports whether user may see the event: everyone sees public
+// events, only the owner and administrators see the others. user may be nil
+// for an anonymous caller.
+func (e *Event) VisibleTo(user *User) bool {
+	return e.Public() || user != nil && (user.IsAdmin || user.ID == e.OwnerID)
+}
+
+// eventTransitions lists the statuses an event may move to from each status.
+// Cancelled and completed events are final.
+var eventTransitions = map[string][]string{
+	EventStatusDraft:     {EventStatusScheduled, EventStatusPublished, EventStatusCancelled},
+	EventStatusScheduled: {EventStatusDraft, EventStatusPublished, EventStatusCancelled},
+	EventStatusPublished: {EventStatusCancelled, EventStatusCompleted},
+}
+
+var transitionRevisions = map[string]string{
+	EventStatusDraft:     RevisionUnscheduled,
+	EventStatusScheduled: RevisionScheduled,
+	EventStatusPublished: RevisionPublished,
+	EventStatusCancelled: RevisionCancelled,
+	EventStatusCompleted: RevisionCompleted,
+}
+
+// CanTransition reports whether an event in status from may move to status to.
+func CanTransition(from, to string) bool {
+	return slices.Contains(eventTransitions[from], to)
+}
+
+// StatusChange describes a transition of an event to a new status. PublishAt
+// is only used when scheduling and CancelReason only when cancelling.
+type StatusChange struct {
+	Status       string
+	PublishAt    *time.Time
+	CancelReason string
+}
+
+// Transition moves the event to a new status, records the change as a
+// revision and returns the updated event. It returns ErrNotFound if there is
+// no such event and ErrInvalidTransition if the event's current status does
+// not allow the change.
+func (m *EventModel) Transition(ctx context.Context, id, actorID int, change StatusChange) (_ *Event, err error) {
+	ctx, done := m.instrument(ctx, "event", "Transition", m.timeouts.Write)
+	defer done(&err)
+
+	return m.transition(ctx, id, actorID, change)
+}
+
+// PublishDue publishes every scheduled event whose publish time has passed
+// and returns the published events.
+func (m *EventModel) PublishDue(ctx context.Context, now time.Time) (_ []*Event, err error) {
+	ctx, done := m.instrument(ctx, "event", "PublishDue", m.timeouts.Batch)
+	defer done(&err)
+
+	query := `
+		SELECT ` + eventColumns + ` FROM events e
+		WHERE e.status = $1 AND e.publish_at <= $2 AND e.deleted_at IS NULL
+		ORDER BY e.publish_at
+	`
+
+	due, err := m.queryEvents(ctx, query, EventStatusScheduled, m.dialect.timestamp(&now))
+	if err != nil {
+		return nil, err
+	}
+
+	published := []*Event{}
+
+	for _, event := range due {
+		event, err := m.transition(ctx, event.ID, SystemActorID, StatusChange{Status: EventStatusPublished})
+		if errors.Is(err, ErrInvalidTransition) || errors.Is(err, ErrNotFound) {
+			// The owner changed or purged the event after it was selected.
+			continue
+		}
+		if err != nil {
+			return published, err
+		}
+
+		published = append(published, event)
+	}
+
+	return published, nil
+}
+
+func (m *EventModel) transition(ctx context.Context, id, actorID int, change StatusChange) (*Event, error) {
+	tx, err := m.db.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := getEventTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if before.DeletedAt != nil || !CanTransition(before.Status, change.Status) {
+		return nil, ErrInvalidTransition
+	}
+
+	publishAt := before.PublishAt
+	var cancelReason any
+
+	switch change.Status {
+	case EventStatusDraft:
+		publishAt = nil
+	case EventStatusScheduled:
+		if change.PublishAt == nil {
+			return nil, ErrInvalidTransition
+		}
+		publishAt = change.PublishAt
+	case EventStatusPublished:
+		now := time.Now()
+		publishAt = &now
+	case EventStatusCancelled:
+		cancelReason = change.CancelReason
+	}
+
+	query := `
+		UPDATE events SET status = $1, publish_at = $2, cancel_reason = $3
+		WHERE id = $4 AND status = $5
+	`
+
+	res, err := tx.ExecContext(ctx, query, change.Status, m.dialect.timestamp(publishAt), cancelReason, id, before.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if rowsAffected == 0 {
+		return nil, ErrInvalidTransition
+	}
+
+	if err := recordRevision(ctx, tx, id, actorID, transitionRevisions[change.Status], before); err != nil {
+		return nil, err
+	}
+
+	after, err := getEventTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return after, tx.Commit()
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package database
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile waits for an exclusive lock on the file at path, creating it if
+// needed, and returns a function that releases the lock.
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,1125 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewMemoryModels returns repositories that keep their data in memory. They
+// behave like the SQLite models, including the revisions they record and the
+// categories seeded by the migrations, and are safe for concurrent use. They
+// are meant for tests.
+func NewMemoryModels() Models {
+	store := &memoryStore{memoryTables: memoryTables{
+		users:      map[int]*User{},
+		events:     map[int]*Event{},
+		attendees:  map[int]*Attendee{},
+		revisions:  map[int]*Revision{},
+		categories: map[int]*Category{},
+		venues:     map[int]*Venue{},
+		lastID:     map[string]int{},
+	}}
+
+	for _, name := range []string{"Technology", "Business", "Music", "Sports", "Arts", "Community"} {
+		id := store.nextID("categories")
+		store.categories[id] = &Category{ID: id, Name: name, Slug: slugify(name)}
+	}
+
+	return store.models(store.withTx)
+}
+
+// models returns the repositories backed by s, with withTx implementing
+// WithTx.
+func (s *memoryStore) models(withTx func(ctx context.Context, fn func(tx Models) error) error) Models {
+	return Models{
+		Users:      &memoryUserRepository{s},
+		Events:     &memoryEventRepository{s},
+		Attendees:  &memoryAttendeeRepository{s},
+		Revisions:  &memoryRevisionRepository{s},
+		Categories: &memoryCategoryRepository{s},
+		Venues:     &memoryVenueRepository{s},
+		withTx:     withTx,
+	}
+}
+
+// memoryStore holds the rows of every table behind a single lock. Values are
+// copied on the way in and out, so callers never share state with the store.
+type memoryStore struct {
+	mu sync.RWMutex
+	// tx is held by the transaction of WithTx that is running.
+	tx sync.Mutex
+
+	memoryTables
+}
+
+// memoryTables are the rows of every table.
+type memoryTables struct {
+	users      map[int]*User
+	events     map[int]*Event
+	attendees  map[int]*Attendee
+	revisions  map[int]*Revision
+	categories map[int]*Category
+	venues     map[int]*Venue
+
+	// lastID holds the last id handed out per table, like AUTOINCREMENT.
+	lastID map[string]int
+}
+
+// read and write take the store lock for a method call once they have checked
+// that the caller is still waiting for the result.
+func (s *memoryStore) read(ctx context.Context) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	s.mu.RLock()
+	return s.mu.RUnlock, nil
+}
+
+func (s *memoryStore) write(ctx context.Context) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	s.mu.Lock()
+	return s.mu.Unlock, nil
+}
+
+// withTx implements Models.WithTx for the memory models. Transactions run one
+// at a time and are rolled back by restoring a copy of the tables taken when
+// they started. Unlike with the SQL models, calls outside of a transaction
+// see its changes before it ends.
+func (s *memoryStore) withTx(ctx context.Context, fn func(tx Models) error) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(ctx, err)
+	}
+
+	s.tx.Lock()
+	defer s.tx.Unlock()
+
+	return s.runTx(ctx, fn)
+}
+
+// runTx runs fn in a transaction, or in a savepoint when nested in one.
+func (s *memoryStore) runTx(_ context.Context, fn func(tx Models) error) error {
+	s.mu.RLock()
+	saved := s.memoryTables.clone()
+	s.mu.RUnlock()
+
+	if err := fn(s.models(s.runTx)); err != nil {
+		s.mu.Lock()
+		s.memoryTables = saved
+		s.mu.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// clone copies the tables deeply enough that changes to t do not show in the
+// copy. Revisions are never changed once stored, so they are shared.
+func (t *memoryTables) clone() memoryTables {
+	return memoryTables{
+		users:      cloneRows(t.users, clonePointer),
+		events:     cloneRows(t.events, copyEvent),
+		attendees:  cloneRows(t.attendees, clonePointer),
+		revisions:  maps.Clone(t.revisions),
+		categories: cloneRows(t.categories, clonePointer),
+		venues:     cloneRows(t.venues, copyVenue),
+		lastID:     maps.Clone(t.lastID),
+	}
+}
+
+func cloneRows[T any](rows map[int]*T, clone func(*T) *T) map[int]*T {
+	c := make(map[int]*T, len(rows))
+	for id, row := range rows {
+		c[id] = clone(row)
+	}
+
+	return c
+}
+
+func (s *memoryStore) nextID(table string) int {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+// sortedIDs returns the keys of rows in ascending order, the order SQLite
+// returns rows in when a query does not ask for one.
+func sortedIDs[T any](rows map[int]T) []int {
+	ids := make([]int, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	return ids
+}
+
+// now returns the current time at the precision of CURRENT_TIMESTAMP.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// storedTime returns t as the database returns it after storing it as a
+// timestamp.
+func storedTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	stored := t.UTC().Truncate(time.Second)
+	return &stored
+}
+
+func uniqueViolation(column string) error {
+	return fmt.Errorf("%w: UNIQUE constraint failed: %s", ErrConflict, column)
+}
+
+func clonePointer[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+
+	v := *p
+	return &v
+}
+
+func copyEvent(event *Event) *Event {
+	c := *event
+	c.Tags = append([]string{}, event.Tags...)
+	c.VenueID = clonePointer(event.VenueID)
+	c.CategoryID = clonePointer(event.CategoryID)
+	c.PublishAt = clonePointer(event.PublishAt)
+	c.CancelReason = clonePointer(event.CancelReason)
+	c.DeletedAt = clonePointer(event.DeletedAt)
+	c.DistanceKm = clonePointer(event.DistanceKm)
+
+	return &c
+}
+
+func copyVenue(venue *Venue) *Venue {
+	c := *venue
+	c.OwnerID = clonePointer(venue.OwnerID)
+	c.Latitude = clonePointer(venue.Latitude)
+	c.Longitude = clonePointer(venue.Longitude)
+	c.Capacity = clonePointer(venue.Capacity)
+
+	return &c
+}
+
+// copyRevision copies a revision through JSON, the way the SQLite models
+// store its changes and snapshot.
+func copyRevision(revision *Revision) (*Revision, error) {
+	data, err := json.Marshal(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Revision
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// event returns the stored event with the given id, including trashed
+// events, or ErrNotFound like getEventTx.
+func (s *memoryStore) event(id int) (*Event, error) {
+	event, ok := s.events[id]
+	if !ok {
+		return nil, notFound("event")
+	}
+
+	return event, nil
+}
+
+func (s *memoryStore) insertRevision(revision *Revision) error {
+	if revision.Changes == nil {
+		revision.Changes = map[string]FieldChange{}
+	}
+
+	revision.ID = s.nextID("event_revisions")
+	revision.CreatedAt = now()
+
+	stored, err := copyRevision(revision)
+	if err != nil {
+		return err
+	}
+
+	s.revisions[revision.ID] = stored
+	return nil
+}
+
+// recordRevision mirrors recordRevision for the event's current state.
+func (s *memoryStore) recordRevision(eventID, actorID int, action string, before *Event) error {
+	after, err := s.event(eventID)
+	if err != nil {
+		return err
+	}
+
+	changes, err := diffEvents(before, after)
+	if err != nil {
+		return err
+	}
+
+	return s.insertRevision(&Revision{
+		EventID:  eventID,
+		ActorID:  actorID,
+		Action:   action,
+		Changes:  changes,
+		Snapshot: copyEvent(after),
+	})
+}
+
+type memoryUserRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryUserRepository) Insert(ctx context.Context, user *User) error {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, existing := range r.store.users {
+		if existing.Email == user.Email {
+			return uniqueViolation("users.email")
+		}
+	}
+
+	user.ID = r.store.nextID("users")
+
+	stored := *user
+	r.store.users[user.ID] = &stored
+
+	return nil
+}
+
+func (r *memoryUserRepository) GetByID(ctx context.Context, userID int) (*User, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	user, ok := r.store.users[userID]
+	if !ok {
+		return nil, notFound("user")
+	}
+
+	c := *user
+	return &c, nil
+}
+
+func (r *memoryUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for _, user := range r.store.users {
+		if user.Email == email {
+			c := *user
+			return &c, nil
+		}
+	}
+
+	return nil, notFound("user")
+}
+
+func (r *memoryUserRepository) SetAdmin(ctx context.Context, userID int, admin bool) error {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	user, ok := r.store.users[userID]
+	if !ok {
+		return notFound("user")
+	}
+
+	user.IsAdmin = admin
+	return nil
+}
+
+type memoryEventRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryEventRepository) Insert(ctx context.Context, event *Event) error {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if event.Status == "" {
+		event.Status = EventStatusDraft
+	}
+
+	event.ID = r.store.nextID("events")
+	event.Tags = NormalizeTags(event.Tags)
+
+	stored := copyEvent(event)
+	stored.PublishAt = nil
+	stored.CancelReason = nil
+	stored.DeletedAt = nil
+	stored.DistanceKm = nil
+	r.store.events[event.ID] = stored
+
+	return r.store.recordRevision(event.ID, event.OwnerID, RevisionCreated, nil)
+}
+
+func (r *memoryEventRepository) GetAll(ctx context.Context, filter EventFilter) ([]*Event, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return r.store.listed(filter), nil
+}
+
+// listed returns copies of the publicly listed events matching the filter,
+// like EventFilter.where together with filterByDistance.
+func (s *memoryStore) listed(filter EventFilter) []*Event {
+	categoryID := 0
+	if filter.Category != "" {
+		for _, category := range s.categories {
+			if category.Slug == filter.Category {
+				categoryID = category.ID
+			}
+		}
+	}
+
+	tags := NormalizeTags(filter.Tags)
+	events := []*Event{}
+
+	for _, id := range sortedIDs(s.events) {
+		event := s.events[id]
+
+		if event.DeletedAt != nil || !slices.Contains(publicStatuses, event.Status) {
+			continue
+		}
+		if filter.Category != "" && (event.CategoryID == nil || *event.CategoryID != categoryID) {
+			continue
+		}
+		if !containsAll(event.Tags, tags) {
+			continue
+		}
+
+		listed := copyEvent(event)
+
+		if filter.Near != nil {
+			distance, ok := s.venueDistance(event.VenueID, *filter.Near)
+			if !ok || distance > filter.RadiusKm {
+				continue
+			}
+			listed.DistanceKm = &distance
+		}
+
+		events = append(events, listed)
+	}
+
+	if filter.Near != nil {
+		slices.SortStableFunc(events, func(a, b *Event) int {
+			return cmp.Compare(*a.DistanceKm, *b.DistanceKm)
+		})
+	}
+
+	return events
+}
+
+func containsAll(values, wanted []string) bool {
+	for _, value := range wanted {
+		if !slices.Contains(values, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *memoryStore) venueDistance(venueID *int, center GeoPoint) (float64, bool) {
+	if venueID == nil {
+		return 0, false
+	}
+
+	venue, ok := s.venues[*venueID]
+	if !ok || venue.Latitude == nil || venue.Longitude == nil {
+		return 0, false
+	}
+
+	return DistanceKm(center, GeoPoint{Latitude: *venue.Latitude, Longitude: *venue.Longitude}), true
+}
+
+func (r *memoryEventRepository) Facets(ctx context.Context, filter EventFilter) (*Facets, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return countFacets(r.store.listed(filter), r.store.categories), nil
+}
+
+// compareNoCase orders strings like ORDER BY LOWER(name) does.
+func compareNoCase(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func (r *memoryEventRepository) Get(ctx context.Context, id int) (*Event, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	event, ok := r.store.events[id]
+	if !ok || event.DeletedAt != nil {
+		return nil, notFound("event")
+	}
+
+	return copyEvent(event), nil
+}
+
+func (r *memoryEventRepository) GetDeleted(ctx context.Context, id int) (*Event, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	event, ok := r.store.events[id]
+	if !ok || event.DeletedAt == nil {
+		return nil, notFound("event")
+	}
+
+	return copyEvent(event), nil
+}
+
+func (r *memoryEventRepository) Update(ctx context.Context, event *Event, actorID int) error {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return r.store.update(event, actorID, RevisionUpdated)
+}
+
+func (r *memoryEventRepository) Revert(ctx context.Context, eventID, revisionID, actorID int) (*Event, error) {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	revision, ok := r.store.revisions[revisionID]
+	if !ok || revision.EventID != eventID || revision.Snapshot == nil {
+		return nil, notFound("revision")
+	}
+
+	reverted := copyEvent(revision.Snapshot)
+	reverted.ID = eventID
+
+	if err := r.store.update(reverted, actorID, RevisionReverted); err != nil {
+		return nil, err
+	}
+
+	event, err := r.store.event(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyEvent(event), nil
+}
+
+func (s *memoryStore) update(event *Event, actorID int, action string) error {
+	stored, err := s.event(event.ID)
+	if err != nil {
+		return err
+	}
+	if stored.DeletedAt != nil {
+		return notFound("event")
+	}
+
+	before := copyEvent(stored)
+
+	event.Tags = NormalizeTags(event.Tags)
+
+	stored.Name = event.Name
+	stored.Description = event.Description
+	stored.Date = event.Date
+	stored.Location = event.Location
+	stored.VenueID = clonePointer(event.VenueID)
+	stored.CategoryID = clonePointer(event.CategoryID)
+	stored.Tags = append([]string{}, event.Tags...)
+
+	return s.recordRevision(event.ID, actorID, action, before)
+}
+
+func (r *memoryEventRepository) Delete(ctx context.Context, id, actorID int) error {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	event, err := r.store.event(id)
+	if err != nil {
+		return err
+	}
+	if event.DeletedAt != nil {
+		return notFound("event")
+	}
+
+	before := copyEvent(event)
+	deletedAt := now()
+	event.DeletedAt = &deletedAt
+
+	return r.store.recordRevision(id, actorID, RevisionDeleted, before)
+}
+
+func (r *memoryEventRepository) Restore(ctx context.Context, id, actorID int) error {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	event, err := r.store.event(id)
+	if err != nil {
+		return err
+	}
+	if event.DeletedAt == nil {
+		return notFound("event")
+	}
+
+	before := copyEvent(event)
+	event.DeletedAt = nil
+
+	return r.store.recordRevision(id, actorID, RevisionRestored, before)
+}
+
+func (r *memoryEventRepository) Purge(ctx context.Context, id, actorID int) error {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return r.store.purge(id, actorID)
+}
+
+func (s *memoryStore) purge(id, actorID int) error {
+	event, err := s.event(id)
+	if err != nil {
+		return err
+	}
+	if event.DeletedAt == nil {
+		return notFound("event")
+	}
+
+	for attendeeID, attendee := range s.attendees {
+		if attendee.EventID == id {
+			delete(s.attendees, attendeeID)
+		}
+	}
+
+	delete(s.events, id)
+
+	return s.insertRevision(&Revision{
+		EventID:  id,
+		ActorID:  actorID,
+		Action:   RevisionPurged,
+		Snapshot: event,
+	})
+}
+
+func (r *memoryEventRepository) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	before := *storedTime(&cutoff)
+
+	var purged int64
+
+	for _, id := range sortedIDs(r.store.events) {
+		event := r.store.events[id]
+		if event.DeletedAt == nil || !event.DeletedAt.Before(before) {
+			continue
+		}
+
+		if err := r.store.purge(id, SystemActorID); err != nil {
+			return 0, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+func (r *memoryEventRepository) GetTrashByOwner(ctx context.Context, ownerID int) ([]*Event, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	events := []*Event{}
+
+	for _, id := range sortedIDs(r.store.events) {
+		event := r.store.events[id]
+		if event.OwnerID == ownerID && event.DeletedAt != nil {
+			events = append(events, copyEvent(event))
+		}
+	}
+
+	slices.SortStableFunc(events, func(a, b *Event) int {
+		return b.DeletedAt.Compare(*a.DeletedAt)
+	})
+
+	return events, nil
+}
+
+func (r *memoryEventRepository) GetByAttendee(ctx context.Context, attendeeID int, viewer *User) ([]*Event, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	events := []*Event{}
+
+	for _, id := range sortedIDs(r.store.attendees) {
+		attendee := r.store.attendees[id]
+		if attendee.UserID != attendeeID {
+			continue
+		}
+
+		event, ok := r.store.events[attendee.EventID]
+		if ok && event.DeletedAt == nil && event.VisibleTo(viewer) {
+			events = append(events, copyEvent(event))
+		}
+	}
+
+	slices.SortStableFunc(events, func(a, b *Event) int {
+		return strings.Compare(b.Date, a.Date)
+	})
+
+	return events, nil
+}
+
+func (r *memoryEventRepository) Transition(ctx context.Context, id, actorID int, change StatusChange) (*Event, error) {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return r.store.transition(id, actorID, change)
+}
+
+func (r *memoryEventRepository) PublishDue(ctx context.Context, at time.Time) ([]*Event, error) {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	due := []*Event{}
+	for _, id := range sortedIDs(r.store.events) {
+		event := r.store.events[id]
+		if event.Status == EventStatusScheduled && event.DeletedAt == nil &&
+			event.PublishAt != nil && !event.PublishAt.After(*storedTime(&at)) {
+			due = append(due, event)
+		}
+	}
+
+	slices.SortStableFunc(due, func(a, b *Event) int {
+		return a.PublishAt.Compare(*b.PublishAt)
+	})
+
+	published := []*Event{}
+
+	for _, event := range due {
+		event, err := r.store.transition(event.ID, SystemActorID, StatusChange{Status: EventStatusPublished})
+		if err != nil {
+			return published, err
+		}
+
+		published = append(published, event)
+	}
+
+	return published, nil
+}
+
+func (s *memoryStore) transition(id, actorID int, change StatusChange) (*Event, error) {
+	event, err := s.event(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if event.DeletedAt != nil || !CanTransition(event.Status, change.Status) {
+		return nil, ErrInvalidTransition
+	}
+
+	before := copyEvent(event)
+	publishAt := event.PublishAt
+	var cancelReason *string
+
+	switch change.Status {
+	case EventStatusDraft:
+		publishAt = nil
+	case EventStatusScheduled:
+		if change.PublishAt == nil {
+			return nil, ErrInvalidTransition
+		}
+		publishAt = storedTime(change.PublishAt)
+	case EventStatusPublished:
+		publishedAt := now()
+		publishAt = &publishedAt
+	case EventStatusCancelled:
+		cancelReason = &change.CancelReason
+	}
+
+	event.Status = change.Status
+	event.PublishAt = clonePointer(publishAt)
+	event.CancelReason = clonePointer(cancelReason)
+
+	if err := s.recordRevision(id, actorID, transitionRevisions[change.Status], before); err != nil {
+		return nil, err
+	}
+
+	return copyEvent(event), nil
+}
+
+type memoryAttendeeRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryAttendeeRepository) Insert(ctx context.Context, attendee *Attendee, actorID int) (*Attendee, error) {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	event, err := r.store.event(attendee.EventID)
+	if err != nil {
+		return nil, err
+	}
+
+	attendee.ID = r.store.nextID("attendees")
+
+	stored := *attendee
+	r.store.attendees[attendee.ID] = &stored
+
+	err = r.store.insertRevision(&Revision{
+		EventID:  attendee.EventID,
+		ActorID:  actorID,
+		Action:   RevisionAttendeeAdded,
+		Changes:  map[string]FieldChange{"attendee": {From: nil, To: attendee.UserID}},
+		Snapshot: copyEvent(event),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attendee, nil
+}
+
+func (r *memoryAttendeeRepository) GetByEventAndAttendee(ctx context.Context, eventID, userID int) (*Attendee, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for _, id := range sortedIDs(r.store.attendees) {
+		attendee := r.store.attendees[id]
+		if attendee.EventID == eventID && attendee.UserID == userID {
+			c := *attendee
+			return &c, nil
+		}
+	}
+
+	return nil, notFound("attendee")
+}
+
+func (r *memoryAttendeeRepository) GetAttendeesByEvent(ctx context.Context, eventID int) ([]*User, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	event, ok := r.store.events[eventID]
+	if !ok || event.DeletedAt != nil {
+		return nil, nil
+	}
+
+	var users []*User
+
+	for _, id := range sortedIDs(r.store.attendees) {
+		attendee := r.store.attendees[id]
+		if attendee.EventID != eventID {
+			continue
+		}
+
+		if user, ok := r.store.users[attendee.UserID]; ok {
+			users = append(users, &User{ID: user.ID, Name: user.Name, Email: user.Email})
+		}
+	}
+
+	return users, nil
+}
+
+func (r *memoryAttendeeRepository) Delete(ctx context.Context, eventID, userID, actorID int) error {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var matching []int
+	for id, attendee := range r.store.attendees {
+		if attendee.EventID == eventID && attendee.UserID == userID {
+			matching = append(matching, id)
+		}
+	}
+
+	if len(matching) == 0 {
+		return notFound("attendee")
+	}
+
+	event, err := r.store.event(eventID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range matching {
+		delete(r.store.attendees, id)
+	}
+
+	return r.store.insertRevision(&Revision{
+		EventID:  eventID,
+		ActorID:  actorID,
+		Action:   RevisionAttendeeRemoved,
+		Changes:  map[string]FieldChange{"attendee": {From: userID, To: nil}},
+		Snapshot: copyEvent(event),
+	})
+}
+
+type memoryRevisionRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryRevisionRepository) GetByEvent(ctx context.Context, eventID int) ([]*Revision, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	revisions := []*Revision{}
+
+	ids := sortedIDs(r.store.revisions)
+	slices.Reverse(ids)
+
+	for _, id := range ids {
+		if r.store.revisions[id].EventID != eventID {
+			continue
+		}
+
+		revision, err := copyRevision(r.store.revisions[id])
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+func (r *memoryRevisionRepository) Get(ctx context.Context, id int) (*Revision, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	revision, ok := r.store.revisions[id]
+	if !ok {
+		return nil, notFound("revision")
+	}
+
+	return copyRevision(revision)
+}
+
+type memoryCategoryRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryCategoryRepository) Insert(ctx context.Context, category *Category) error {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	category.Name = strings.TrimSpace(category.Name)
+	category.Slug = slugify(category.Name)
+	if category.Slug == "" {
+		return ErrEmptySlug
+	}
+
+	for _, existing := range r.store.categories {
+		if strings.EqualFold(existing.Name, category.Name) {
+			return uniqueViolation("categories.name")
+		}
+		if existing.Slug == category.Slug {
+			return uniqueViolation("categories.slug")
+		}
+	}
+
+	category.ID = r.store.nextID("categories")
+
+	stored := *category
+	r.store.categories[category.ID] = &stored
+
+	return nil
+}
+
+func (r *memoryCategoryRepository) GetAll(ctx context.Context) ([]*Category, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	categories := []*Category{}
+	for _, id := range sortedIDs(r.store.categories) {
+		c := *r.store.categories[id]
+		categories = append(categories, &c)
+	}
+
+	slices.SortStableFunc(categories, func(a, b *Category) int {
+		return compareNoCase(a.Name, b.Name)
+	})
+
+	return categories, nil
+}
+
+func (r *memoryCategoryRepository) Get(ctx context.Context, id int) (*Category, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	category, ok := r.store.categories[id]
+	if !ok {
+		return nil, notFound("category")
+	}
+
+	c := *category
+	return &c, nil
+}
+
+type memoryVenueRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryVenueRepository) Insert(ctx context.Context, venue *Venue) error {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	venue.ID = r.store.nextID("venues")
+	r.store.venues[venue.ID] = copyVenue(venue)
+
+	return nil
+}
+
+func (r *memoryVenueRepository) GetAll(ctx context.Context) ([]*Venue, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	venues := []*Venue{}
+	for _, id := range sortedIDs(r.store.venues) {
+		venues = append(venues, copyVenue(r.store.venues[id]))
+	}
+
+	slices.SortStableFunc(venues, func(a, b *Venue) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return venues, nil
+}
+
+func (r *memoryVenueRepository) Get(ctx context.Context, id int) (*Venue, error) {
+	unlock, err := r.store.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	venue, ok := r.store.venues[id]
+	if !ok {
+		return nil, notFound("venue")
+	}
+
+	return copyVenue(venue), nil
+}
+
+func (r *memoryVenueRepository) Update(ctx context.Context, venue *Venue) error {
+	unlock, err := r.store.write(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	existing, ok := r.store.venues[venue.ID]
+	if !ok {
+		return notFound("venue")
+	}
+
+	stored := copyVenue(venue)
+	stored.OwnerID = existing.OwnerID
+	r.store.venues[venue.ID] = stored
+
+	return nil
+}
+
+var (
+	_ UserRepository     = (*memoryUserRepository)(nil)
+	_ EventRepository    = (*memoryEventRepository)(nil)
+	_ AttendeeRepository = (*memoryAttendeeRepository)(nil)
+	_ RevisionRepository = (*memoryRevisionRepository)(nil)
+	_ CategoryRepository = (*memoryCategoryRepository)(nil)
+	_ VenueRepository    = (*memoryVenueRepository)(nil)
+)
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"rest-api-go-gin/internal/database/migrations"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// NewMigrator opens the migrations of the dialect on the database at dsn. It
+// uses the migrations embedded in the binary, or those in dir if dir is not
+// empty. The migrator has its own connection, which Close releases.
+func NewMigrator(dialect Dialect, dsn, dir string) (*migrate.Migrate, error) {
+	var src source.Driver
+	var err error
+
+	if dir != "" {
+		src, err = iofs.New(os.DirFS(dir), ".")
+	} else {
+		src, err = iofs.New(migrations.FS, string(dialect))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open migrations: %w", err)
+	}
+
+	db, err := sql.Open(dialect.DriverName(), dsn)
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+
+	var instance migratedb.Driver
+	if dialect == Postgres {
+		instance, err = pgx.WithInstance(db, &pgx.Config{})
+	} else {
+		instance, err = sqlite.WithInstance(db, &sqlite.Config{})
+	}
+	if err != nil {
+		src.Close()
+		db.Close()
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, string(dialect), instance)
+	if err != nil {
+		src.Close()
+		instance.Close()
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// MigrateUp applies the pending embedded migrations to the database at dsn and
+// returns the version it started from. Only one process migrates at a time:
+// PostgreSQL holds an advisory lock while migrating and SQLite a lock on a
+// file next to the database. MigrateUp fails without changing anything if the
+// schema is newer than SchemaVersion.
+func MigrateUp(dialect Dialect, dsn string) (from int, err error) {
+	if dialect == SQLite {
+		unlock, err := lockFile(sqliteLockPath(dsn))
+		if err != nil {
+			return 0, fmt.Errorf("lock database for migrations: %w", err)
+		}
+		defer unlock()
+	}
+
+	m, err := NewMigrator(dialect, dsn, "")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		srcErr, dbErr := m.Close()
+		err = errors.Join(err, srcErr, dbErr)
+	}()
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return 0, err
+	}
+	from = int(version)
+
+	if dirty {
+		return from, fmt.Errorf("schema version %d is dirty", from)
+	}
+	if from > SchemaVersion {
+		return from, fmt.Errorf("schema version is %d, newer than %d expected by this build", from, SchemaVersion)
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return from, err
+	}
+
+	return from, nil
+}
+
+// CheckSchemaNotNewer returns an error if the database schema is at a
+// version this build does not know. An older schema passes, since it can be
+// migrated while the API runs, and so does a database whose version cannot
+// be read yet; the migrations health check reports both.
+func CheckSchemaNotNewer(ctx context.Context, db *sql.DB) error {
+	version, _, err := CurrentSchemaVersion(ctx, db)
+	if err != nil {
+		return nil
+	}
+
+	if version > SchemaVersion {
+		return fmt.Errorf("schema version is %d, newer than %d expected by this build", version, SchemaVersion)
+	}
+
+	return nil
+}
+
+// sqliteLockPath returns the lock file of the SQLite database at dsn.
+func sqliteLockPath(dsn string) string {
+	file := SQLiteFile(dsn)
+	if file == "" || file == ":memory:" {
+		return path.Join(os.TempDir(), "rest-api-go-gin-memory.migrate.lock")
+	}
+
+	return file + ".migrate.lock"
+}
@@ -0,0 +1,9 @@
+// Package migrations embeds the schema migrations so that binaries can apply
+// them without the source tree. Each dialect has its own directory of
+// migrations with the same versions.
+package migrations
+
+import "embed"
+
+//go:embed sqlite/*.sql postgres/*.sql
+var FS embed.FS
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS event_revisions;
+DROP FUNCTION IF EXISTS event_revisions_immutable();
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS event_revisions (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    event_id BIGINT NOT NULL,
+    actor_id BIGINT NOT NULL,
+    action TEXT NOT NULL,
+    changes TEXT NOT NULL,
+    snapshot TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_revisions_event_id ON event_revisions (event_id, id);
+
+CREATE OR REPLACE FUNCTION event_revisions_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'event revisions are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER event_revisions_immutable_update
+BEFORE UPDATE ON event_revisions
+FOR EACH ROW EXECUTE FUNCTION event_revisions_immutable();
+
+CREATE TRIGGER event_revisions_immutable_delete
+BEFORE DELETE ON event_revisions
+FOR EACH ROW EXECUTE FUNCTION event_revisions_immutable();
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_events_status_publish_at;
+
+ALTER TABLE events DROP COLUMN cancel_reason;
+ALTER TABLE events DROP COLUMN publish_at;
+ALTER TABLE events DROP COLUMN status;
\ No newline at end of file
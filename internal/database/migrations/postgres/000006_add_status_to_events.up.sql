@@ -0,0 +1,6 @@
+ALTER TABLE events ADD COLUMN status TEXT NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'scheduled', 'published', 'cancelled', 'completed'));
+ALTER TABLE events ADD COLUMN publish_at TIMESTAMPTZ;
+ALTER TABLE events ADD COLUMN cancel_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_events_status_publish_at ON events (status, publish_at);
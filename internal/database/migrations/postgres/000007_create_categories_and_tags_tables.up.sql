@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL UNIQUE
+);
+
+-- Category names are unique regardless of case, like COLLATE NOCASE in SQLite.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_name ON categories (LOWER(name));
+
+INSERT INTO categories (name, slug) VALUES
+    ('Technology', 'technology'),
+    ('Business', 'business'),
+    ('Music', 'music'),
+    ('Sports', 'sports'),
+    ('Arts', 'arts'),
+    ('Community', 'community');
+
+ALTER TABLE events ADD COLUMN category_id BIGINT REFERENCES categories(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_events_category_id ON events (category_id);
+
+-- Tag names are stored lowercased by the application.
+CREATE TABLE IF NOT EXISTS tags (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS event_tags (
+    event_id BIGINT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (event_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_tags_tag_id ON event_tags (tag_id);
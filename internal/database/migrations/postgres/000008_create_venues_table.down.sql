@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_events_venue_id;
+ALTER TABLE events DROP COLUMN venue_id;
+
+DROP INDEX IF EXISTS idx_venues_coordinates;
+DROP TABLE IF EXISTS venues;
\ No newline at end of file
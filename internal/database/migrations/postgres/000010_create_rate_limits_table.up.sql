@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS rate_limits (
+    bucket TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at BIGINT NOT NULL,
+    full_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limits_full_at ON rate_limits (full_at);
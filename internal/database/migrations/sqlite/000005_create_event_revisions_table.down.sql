@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS event_revisions_immutable_delete;
+DROP TRIGGER IF EXISTS event_revisions_immutable_update;
+DROP TABLE IF EXISTS event_revisions;
\ No newline at end of file
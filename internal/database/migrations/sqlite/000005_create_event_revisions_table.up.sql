@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS event_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    actor_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    changes TEXT NOT NULL,
+    snapshot TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_revisions_event_id ON event_revisions (event_id, id);
+
+CREATE TRIGGER IF NOT EXISTS event_revisions_immutable_update
+BEFORE UPDATE ON event_revisions
+BEGIN
+    SELECT RAISE(ABORT, 'event revisions are immutable');
+END;
+
+CREATE TRIGGER IF NOT EXISTS event_revisions_immutable_delete
+BEFORE DELETE ON event_revisions
+BEGIN
+    SELECT RAISE(ABORT, 'event revisions are immutable');
+END;
\ No newline at end of file
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_event_tags_tag_id;
+DROP TABLE IF EXISTS event_tags;
+DROP TABLE IF EXISTS tags;
+
+DROP INDEX IF EXISTS idx_events_category_id;
+ALTER TABLE events DROP COLUMN category_id;
+
+DROP TABLE IF EXISTS categories;
\ No newline at end of file
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    slug TEXT NOT NULL UNIQUE
+);
+
+INSERT INTO categories (name, slug) VALUES
+    ('Technology', 'technology'),
+    ('Business', 'business'),
+    ('Music', 'music'),
+    ('Sports', 'sports'),
+    ('Arts', 'arts'),
+    ('Community', 'community');
+
+ALTER TABLE events ADD COLUMN category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_events_category_id ON events (category_id);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE COLLATE NOCASE
+);
+
+CREATE TABLE IF NOT EXISTS event_tags (
+    event_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (event_id, tag_id),
+    FOREIGN KEY (event_id) REFERENCES events(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_tags_tag_id ON event_tags (tag_id);
\ No newline at end of file
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS venues (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_id INTEGER,
+    name TEXT NOT NULL,
+    address TEXT NOT NULL,
+    latitude REAL,
+    longitude REAL,
+    capacity INTEGER,
+    accessibility_notes TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (owner_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_venues_coordinates ON venues (latitude, longitude);
+
+ALTER TABLE events ADD COLUMN venue_id INTEGER REFERENCES venues(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_events_venue_id ON events (venue_id);
+
+-- Existing free-text locations become unowned venues without coordinates.
+-- Only administrators can geocode them through the venues API afterwards.
+INSERT INTO venues (name, address)
+SELECT DISTINCT location, location FROM events WHERE TRIM(location) <> '';
+
+UPDATE events SET venue_id = (
+    SELECT MIN(v.id) FROM venues v WHERE v.owner_id IS NULL AND v.address = events.location
+)
+WHERE venue_id IS NULL;
\ No newline at end of file
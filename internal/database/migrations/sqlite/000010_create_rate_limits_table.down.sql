@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_rate_limits_full_at;
+DROP TABLE IF EXISTS rate_limits;
@@ -1,17 +1,136 @@
 package database
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("rest-api-go-gin/internal/database")
 
 type Models struct {
-	Users     UserModel
-	Events    EventModel
-	Attendees AttendeeModel
+	Users      UserRepository
+	Events     EventRepository
+	Attendees  AttendeeRepository
+	Revisions  RevisionRepository
+	Categories CategoryRepository
+	Venues     VenueRepository
+
+	// withTx implements WithTx; nil runs the function without a transaction.
+	withTx func(ctx context.Context, fn func(tx Models) error) error
+}
+
+// WithTx runs fn with models whose methods all run in one transaction, which
+// is committed if fn returns nil and rolled back otherwise. Its error is
+// returned unchanged. When the database is busy or the transaction cannot be
+// serialized with a concurrent one, fn runs again in a new transaction, so it
+// must not have effects outside of the models it is given. Those models must
+// only be used within fn; calling WithTx on them runs the nested function in a
+// savepoint of the same transaction.
+func (m Models) WithTx(ctx context.Context, fn func(tx Models) error) error {
+	if m.withTx == nil {
+		return fn(m)
+	}
+
+	return m.withTx(ctx, fn)
 }
 
-func NewModels(db *sql.DB) Models {
+// QueryObserver is told how long each call to a model method took.
+type QueryObserver func(model, method string, duration time.Duration)
+
+// Options configures the SQL models. Dialect defaults to SQLite and Observer
+// may be nil. ReadDB, if set, serves the queries that do not write, so that
+// reads do not wait for SQLite's single writer.
+type Options struct {
+	Dialect  Dialect
+	Timeouts Timeouts
+	Observer QueryObserver
+	ReadDB   *sql.DB
+}
+
+// NewModels returns the repositories backed by db.
+func NewModels(db *sql.DB, options Options) Models {
+	m := model{
+		dialect:  options.Dialect,
+		observer: options.Observer,
+		timeouts: options.Timeouts,
+		db:       dbConn{db},
+		readDB:   options.ReadDB,
+	}
+	if m.dialect == "" {
+		m.dialect = SQLite
+	}
+	if options.ReadDB == nil {
+		m.readDB = db
+	}
+
+	return m.models()
+}
+
+// models returns the repositories that run their statements on m.
+func (m model) models() Models {
 	return Models{
-		Users:     UserModel{DB: db},
-		Events:    EventModel{DB: db},
-		Attendees: AttendeeModel{DB: db},
+		Users:      &UserModel{model: m},
+		Events:     &EventModel{model: m},
+		Attendees:  &AttendeeModel{model: m},
+		Revisions:  &RevisionModel{model: m},
+		Categories: &CategoryModel{model: m},
+		Venues:     &VenueModel{model: m},
+		withTx:     m.withTx,
+	}
+}
+
+// model holds what every SQL model needs.
+type model struct {
+	dialect  Dialect
+	observer QueryObserver
+	timeouts Timeouts
+	// db runs the statements that write and starts transactions. Within
+	// WithTx it is the transaction.
+	db conn
+	// readDB runs the queries outside of transactions that only read. Within
+	// WithTx it is the transaction as well.
+	readDB querier
+}
+
+// instrument starts a span for a model method, begins timing it and bounds it
+// by timeout. The statements run with the returned context become children of
+// that span. The returned function must be deferred with a pointer to the
+// method's error: it turns context failures into ErrTimeout or ErrCanceled,
+// records the error on the span, ends it and reports the duration.
+func (m model) instrument(ctx context.Context, name, method string, timeout time.Duration) (context.Context, func(*error)) {
+	ctx, span := tracer.Start(ctx, name+"."+method,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("db.system", string(m.dialect)),
+			attribute.String("db.model", name),
+		),
+	)
+
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	start := time.Now()
+
+	return ctx, func(errp *error) {
+		*errp = translateError(ctx, *errp)
+		cancel()
+
+		if *errp != nil {
+			span.RecordError(*errp)
+			span.SetStatus(codes.Error, (*errp).Error())
+		}
+		span.End()
+
+		if m.observer != nil {
+			m.observer(name, method, time.Since(start))
+		}
 	}
 }
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SQLitePragmas are the connection settings of a SQLite database. They are
+// applied to every new connection through the data source name.
+type SQLitePragmas struct {
+	// JournalMode is usually WAL, which lets readers work while a write is
+	// in progress. It is stored in the database file, so only the writer
+	// sets it.
+	JournalMode string
+	// BusyTimeout is how long a connection waits for a lock held by another
+	// one before failing with SQLITE_BUSY.
+	BusyTimeout time.Duration
+	// ForeignKeys enforces REFERENCES clauses, including ON DELETE actions.
+	ForeignKeys bool
+	// Synchronous is how often SQLite waits for the disk; NORMAL is safe
+	// with WAL.
+	Synchronous string
+}
+
+var DefaultSQLitePragmas = SQLitePragmas{
+	JournalMode: "WAL",
+	BusyTimeout: 5 * time.Second,
+	ForeignKeys: true,
+	Synchronous: "NORMAL",
+}
+
+// SQLiteDSN returns the data source name that opens the SQLite database file
+// at path with the pragmas. Write connections start their transactions with
+// BEGIN IMMEDIATE, so that a transaction that reads before writing waits for
+// the lock up front instead of failing halfway. Read-only connections refuse
+// to write.
+//
+// Parameters in the query of path are kept, and a _pragma there replaces the
+// configured pragma of the same name. The driver runs the pragmas in
+// alphabetical order, so the last one given does not win by itself.
+func SQLiteDSN(path string, pragmas SQLitePragmas, readOnly bool) string {
+	file, query, _ := strings.Cut(strings.TrimPrefix(path, "file:"), "?")
+
+	// ParseURL has rejected malformed queries.
+	params, _ := url.ParseQuery(query)
+
+	given := map[string]bool{}
+	for _, pragma := range params["_pragma"] {
+		given[pragmaName(pragma)] = true
+	}
+
+	add := func(pragma string) {
+		if !given[pragmaName(pragma)] {
+			params.Add("_pragma", pragma)
+		}
+	}
+
+	if pragmas.JournalMode != "" && !readOnly {
+		add(fmt.Sprintf("journal_mode(%s)", pragmas.JournalMode))
+	}
+	add(fmt.Sprintf("busy_timeout(%d)", pragmas.BusyTimeout.Milliseconds()))
+	if pragmas.ForeignKeys {
+		add("foreign_keys(1)")
+	} else {
+		add("foreign_keys(0)")
+	}
+	if pragmas.Synchronous != "" {
+		add(fmt.Sprintf("synchronous(%s)", pragmas.Synchronous))
+	}
+
+	if readOnly {
+		params["_pragma"] = slices.DeleteFunc(params["_pragma"], func(pragma string) bool {
+			return pragmaName(pragma) == "query_only"
+		})
+		params.Add("_pragma", "query_only(1)")
+	} else {
+		params.Set("_txlock", "immediate")
+	}
+
+	return "file:" + file + "?" + params.Encode()
+}
+
+// SQLiteFile returns the file path of the SQLite database at dsn, without
+// the parameters.
+func SQLiteFile(dsn string) string {
+	file, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	return file
+}
+
+// pragmaName returns the name of the pragma in a _pragma parameter, such as
+// busy_timeout for busy_timeout(5000) or busy_timeout = 5000.
+func pragmaName(pragma string) string {
+	name, _, _ := strings.Cut(pragma, "(")
+	name, _, _ = strings.Cut(name, "=")
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// PoolOptions sizes a database/sql connection pool. Zero values keep the
+// database/sql defaults.
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// Apply configures the pool of db.
+func (p PoolOptions) Apply(db *sql.DB) {
+	if p.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(p.MaxOpenConns)
+	}
+	if p.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(p.MaxIdleConns)
+	}
+	if p.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	}
+	if p.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+	}
+}
+
+// SQLiteWriterPool is the pool of the SQLite write connection. SQLite allows
+// one writer at a time, so more connections would only wait on each other
+// and fail with SQLITE_BUSY when the wait is too long.
+var SQLiteWriterPool = PoolOptions{MaxOpenConns: 1, MaxIdleConns: 1}
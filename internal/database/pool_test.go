@@ -0,0 +1,100 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		dialect Dialect
+		dsn     string
+		wantErr bool
+	}{
+		{url: "sqlite://./data.db", dialect: SQLite, dsn: "./data.db"},
+		{url: "sqlite3:///var/lib/api/data.db", dialect: SQLite, dsn: "/var/lib/api/data.db"},
+		{url: "sqlite://./data.db?cache=shared", dialect: SQLite, dsn: "./data.db?cache=shared"},
+		{url: "postgres://api@localhost/api?sslmode=disable", dialect: Postgres, dsn: "postgres://api@localhost/api?sslmode=disable"},
+		{url: "sqlite://", wantErr: true},
+		{url: "sqlite://?cache=shared", wantErr: true},
+		{url: "sqlite://./data.db?cache=%zz", wantErr: true},
+		{url: "mysql://localhost/api", wantErr: true},
+		{url: "./data.db", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		dialect, dsn, err := ParseURL(tt.url)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseURL(%q) = %s, %q, want an error", tt.url, dialect, dsn)
+			}
+			continue
+		}
+		if err != nil || dialect != tt.dialect || dsn != tt.dsn {
+			t.Errorf("ParseURL(%q) = %s, %q, %v, want %s, %q", tt.url, dialect, dsn, err, tt.dialect, tt.dsn)
+		}
+	}
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	pragmas := SQLitePragmas{JournalMode: "WAL", BusyTimeout: 5 * time.Second}
+
+	tests := []struct {
+		path     string
+		readOnly bool
+		want     string
+	}{
+		{
+			path: "./data.db",
+			want: "file:./data.db?_pragma=journal_mode%28WAL%29&_pragma=busy_timeout%285000%29&_pragma=foreign_keys%280%29&_txlock=immediate",
+		},
+		{
+			path:     "file:./data.db",
+			readOnly: true,
+			want:     "file:./data.db?_pragma=busy_timeout%285000%29&_pragma=foreign_keys%280%29&_pragma=query_only%281%29",
+		},
+		{
+			path: "./data.db?cache=shared&_pragma=busy_timeout(100)&_pragma=FOREIGN_KEYS = 1",
+			want: "file:./data.db?_pragma=busy_timeout%28100%29&_pragma=FOREIGN_KEYS+%3D+1&_pragma=journal_mode%28WAL%29&_txlock=immediate&cache=shared",
+		},
+		{
+			path:     "./data.db?_pragma=query_only(0)&_txlock=deferred",
+			readOnly: true,
+			want:     "file:./data.db?_pragma=busy_timeout%285000%29&_pragma=foreign_keys%280%29&_pragma=query_only%281%29&_txlock=deferred",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := SQLiteDSN(tt.path, pragmas, tt.readOnly); got != tt.want {
+			t.Errorf("SQLiteDSN(%q, %t) =\n%s\nwant\n%s", tt.path, tt.readOnly, got, tt.want)
+		}
+	}
+}
+
+func TestSQLiteDSNOpens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+
+	db, err := sql.Open("sqlite", SQLiteDSN(path+"?cache=shared&_pragma=busy_timeout(1234)", DefaultSQLitePragmas, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var busyTimeout, foreignKeys int
+	if err := db.QueryRow(`PRAGMA busy_timeout`).Scan(&busyTimeout); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(`PRAGMA foreign_keys`).Scan(&foreignKeys); err != nil {
+		t.Fatal(err)
+	}
+	if busyTimeout != 1234 || foreignKeys != 1 {
+		t.Errorf("busy_timeout = %d, foreign_keys = %d, want 1234 and 1", busyTimeout, foreignKeys)
+	}
+
+	if got := SQLiteFile(path + "?cache=shared"); got != path {
+		t.Errorf("SQLiteFile() = %q, want %q", got, path)
+	}
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// The repositories below are what the API depends on. NewModels backs them
+// with SQLite or PostgreSQL and NewMemoryModels with an in-memory store for
+// tests; both behave the same, which the conformance tests check. Methods that
+// look up or change a single row return ErrNotFound if there is no such row.
+
+type UserRepository interface {
+	Insert(ctx context.Context, user *User) error
+	GetByID(ctx context.Context, userID int) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	SetAdmin(ctx context.Context, userID int, admin bool) error
+}
+
+type EventRepository interface {
+	Insert(ctx context.Context, event *Event) error
+	GetAll(ctx context.Context, filter EventFilter) ([]*Event, error)
+	Facets(ctx context.Context, filter EventFilter) (*Facets, error)
+	// Get only finds live events and GetDeleted only trashed ones.
+	Get(ctx context.Context, id int) (*Event, error)
+	GetDeleted(ctx context.Context, id int) (*Event, error)
+	Update(ctx context.Context, event *Event, actorID int) error
+	Revert(ctx context.Context, eventID, revisionID, actorID int) (*Event, error)
+	Delete(ctx context.Context, id, actorID int) error
+	Restore(ctx context.Context, id, actorID int) error
+	Purge(ctx context.Context, id, actorID int) error
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	GetTrashByOwner(ctx context.Context, ownerID int) ([]*Event, error)
+	// GetByAttendee only returns the events viewer may see, as in
+	// Event.VisibleTo.
+	GetByAttendee(ctx context.Context, attendeeID int, viewer *User) ([]*Event, error)
+	Transition(ctx context.Context, id, actorID int, change StatusChange) (*Event, error)
+	PublishDue(ctx context.Context, now time.Time) ([]*Event, error)
+}
+
+type AttendeeRepository interface {
+	Insert(ctx context.Context, attendee *Attendee, actorID int) (*Attendee, error)
+	GetByEventAndAttendee(ctx context.Context, eventID, userID int) (*Attendee, error)
+	GetAttendeesByEvent(ctx context.Context, eventID int) ([]*User, error)
+	Delete(ctx context.Context, eventID, userID, actorID int) error
+}
+
+type RevisionRepository interface {
+	GetByEvent(ctx context.Context, eventID int) ([]*Revision, error)
+	Get(ctx context.Context, id int) (*Revision, error)
+}
+
+type CategoryRepository interface {
+	Insert(ctx context.Context, category *Category) error
+	GetAll(ctx context.Context) ([]*Category, error)
+	Get(ctx context.Context, id int) (*Category, error)
+}
+
+type VenueRepository interface {
+	Insert(ctx context.Context, venue *Venue) error
+	GetAll(ctx context.Context) ([]*Venue, error)
+	Get(ctx context.Context, id int) (*Venue, error)
+	Update(ctx context.Context, venue *Venue) error
+}
+
+var (
+	_ UserRepository     = (*UserModel)(nil)
+	_ EventRepository    = (*EventModel)(nil)
+	_ AttendeeRepository = (*AttendeeModel)(nil)
+	_ RevisionRepository = (*RevisionModel)(nil)
+	_ CategoryRepository = (*CategoryModel)(nil)
+	_ VenueRepository    = (*VenueModel)(nil)
+)
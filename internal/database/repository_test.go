@@ -0,0 +1,987 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"rest-api-go-gin/internal/database/migrations"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// TestRepositories runs the same conformance tests against every
+// implementation of the repositories.
+func TestRepositories(t *testing.T) {
+	implementations := []struct {
+		name      string
+		newModels func(t *testing.T) Models
+	}{
+		{"sqlite", newSQLiteModels},
+		{"postgres", newPostgresModels},
+		{"memory", func(*testing.T) Models { return NewMemoryModels() }},
+	}
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, m Models)
+	}{
+		{"users", testUsers},
+		{"event visibility", testEventVisibility},
+		{"event filters and facets", testEventFilters},
+		{"trash", testTrash},
+		{"revisions", testRevisions},
+		{"lifecycle", testLifecycle},
+		{"attendees", testAttendees},
+		{"events by attendee", testEventsByAttendee},
+		{"venues", testVenues},
+		{"more events than a query lists", testManyEvents},
+		{"categories", testCategories},
+		{"transactions", testTransactions},
+		{"canceled context", testCanceledContext},
+	}
+
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					test.run(t, impl.newModels(t))
+				})
+			}
+		})
+	}
+}
+
+// newSQLiteModels returns models backed by a fresh database with every
+// migration applied.
+func newSQLiteModels(t *testing.T) Models {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := sql.Open("sqlite", SQLiteDSN(path, DefaultSQLitePragmas, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	SQLiteWriterPool.Apply(db)
+
+	applyMigrations(t, db, SQLite)
+
+	readDB, err := sql.Open("sqlite", SQLiteDSN(path, DefaultSQLitePragmas, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { readDB.Close() })
+
+	return NewModels(db, Options{Timeouts: DefaultTimeouts, ReadDB: readDB})
+}
+
+// newPostgresModels returns models backed by a fresh schema with every
+// migration applied. It uses the database at TEST_POSTGRES_URL, or starts an
+// embedded PostgreSQL server unless the tests run with -short, and skips the
+// test if neither is available.
+func newPostgresModels(t *testing.T) Models {
+	t.Helper()
+
+	databaseURL, err := postgresURL()
+	if err != nil {
+		t.Skipf("PostgreSQL is not available: %v", err)
+	}
+
+	admin, err := sql.Open("pgx", databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("test_%d_%d", os.Getpid(), postgresSchemas.Add(1))
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("drop schema %s: %v", schema, err)
+		}
+	})
+
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+
+	db, err := sql.Open("pgx", u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	applyMigrations(t, db, Postgres)
+
+	return NewModels(db, Options{Dialect: Postgres, Timeouts: DefaultTimeouts})
+}
+
+var (
+	postgresSchemas atomic.Int64
+	embeddedServer  *embeddedpostgres.EmbeddedPostgres
+	// embeddedRuntime holds the binaries and data of the embedded server.
+	embeddedRuntime string
+
+	postgresURL = sync.OnceValues(func() (string, error) {
+		if databaseURL := os.Getenv("TEST_POSTGRES_URL"); databaseURL != "" {
+			return databaseURL, nil
+		}
+
+		if testing.Short() {
+			return "", errors.New("TEST_POSTGRES_URL is not set and -short skips the embedded server")
+		}
+
+		port, err := freePort()
+		if err != nil {
+			return "", err
+		}
+
+		runtime, err := os.MkdirTemp("", "embedded-postgres-")
+		if err != nil {
+			return "", err
+		}
+
+		config := embeddedpostgres.DefaultConfig().
+			Port(port).
+			RuntimePath(runtime).
+			Logger(io.Discard)
+
+		server := embeddedpostgres.NewDatabase(config)
+		if err := server.Start(); err != nil {
+			os.RemoveAll(runtime)
+			return "", fmt.Errorf("start embedded server: %w", err)
+		}
+		embeddedServer = server
+		embeddedRuntime = runtime
+
+		return config.GetConnectionURL() + "?sslmode=disable", nil
+	})
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+
+	if embeddedServer != nil {
+		if err := embeddedServer.Stop(); err != nil {
+			fmt.Fprintln(os.Stderr, "stop embedded PostgreSQL:", err)
+		}
+		if err := os.RemoveAll(embeddedRuntime); err != nil {
+			fmt.Fprintln(os.Stderr, "remove embedded PostgreSQL:", err)
+		}
+	}
+
+	os.Exit(code)
+}
+
+// freePort returns a TCP port that is free right now, so that test runs in
+// parallel do not start their embedded servers on the same port.
+func freePort() (uint32, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	return uint32(listener.Addr().(*net.TCPAddr).Port), nil
+}
+
+// applyMigrations runs every up migration of the dialect against db.
+func applyMigrations(t *testing.T, db *sql.DB, dialect Dialect) {
+	t.Helper()
+
+	scripts, err := fs.Glob(migrations.FS, string(dialect)+"/*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scripts) == 0 {
+		t.Fatalf("no %s migrations found", dialect)
+	}
+	slices.Sort(scripts)
+
+	for _, migration := range scripts {
+		script, err := fs.ReadFile(migrations.FS, migration)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(string(script)); err != nil {
+			t.Fatalf("apply %s: %v", filepath.Base(migration), err)
+		}
+	}
+}
+
+func insertUser(t *testing.T, m Models, email string) *User {
+	t.Helper()
+
+	user := &User{Email: email, Name: "User " + email, Password: "hash"}
+	if err := m.Users.Insert(context.Background(), user); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+
+	return user
+}
+
+// insertEvent inserts an event owned by owner, lets change adjust it first
+// and publishes it.
+func insertEvent(t *testing.T, m Models, owner *User, change func(*Event)) *Event {
+	t.Helper()
+
+	event := &Event{
+		OwnerID:     owner.ID,
+		Name:        "Go meetup",
+		Description: "Monthly meetup about Go",
+		Date:        "2030-01-01",
+		Location:    "Tashkent",
+	}
+	if change != nil {
+		change(event)
+	}
+
+	ctx := context.Background()
+
+	if err := m.Events.Insert(ctx, event); err != nil {
+		t.Fatalf("insert event: %v", err)
+	}
+
+	published, err := m.Events.Transition(ctx, event.ID, owner.ID, StatusChange{Status: EventStatusPublished})
+	if err != nil {
+		t.Fatalf("publish event: %v", err)
+	}
+
+	return published
+}
+
+func eventIDs(events []*Event) []int {
+	ids := []int{}
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+
+	return ids
+}
+
+func testUsers(t *testing.T, m Models) {
+	ctx := context.Background()
+	alice := insertUser(t, m, "alice@example.com")
+
+	byID, err := m.Users.GetByID(ctx, alice.ID)
+	if err != nil || byID == nil {
+		t.Fatalf("GetByID = %v, %v", byID, err)
+	}
+	if byID.Email != alice.Email || byID.Name != alice.Name || byID.Password != alice.Password {
+		t.Errorf("GetByID = %+v, want %+v", byID, alice)
+	}
+
+	byEmail, err := m.Users.GetByEmail(ctx, alice.Email)
+	if err != nil || byEmail == nil || byEmail.ID != alice.ID {
+		t.Errorf("GetByEmail = %v, %v", byEmail, err)
+	}
+
+	if err := m.Users.Insert(ctx, &User{Email: alice.Email, Name: "Other", Password: "hash"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("inserting a duplicate email: err = %v, want ErrConflict", err)
+	}
+
+	if _, err := m.Users.GetByID(ctx, alice.ID+100); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByID of a missing user: err = %v, want ErrNotFound", err)
+	}
+	if _, err := m.Users.GetByEmail(ctx, "nobody@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByEmail of a missing user: err = %v, want ErrNotFound", err)
+	}
+
+	if byID.IsAdmin {
+		t.Error("a new user is an administrator")
+	}
+	if err := m.Users.SetAdmin(ctx, alice.ID, true); err != nil {
+		t.Fatalf("SetAdmin: %v", err)
+	}
+	if admin, err := m.Users.GetByEmail(ctx, alice.Email); err != nil || !admin.IsAdmin {
+		t.Errorf("GetByEmail after SetAdmin = %+v, %v", admin, err)
+	}
+	if err := m.Users.SetAdmin(ctx, alice.ID+100, true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetAdmin of a missing user: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testEventVisibility(t *testing.T, m Models) {
+	ctx := context.Background()
+	owner := insertUser(t, m, "owner@example.com")
+
+	draft := &Event{OwnerID: owner.ID, Name: "Draft", Description: "Not listed yet", Date: "2030-01-01", Location: "Tashkent"}
+	if err := m.Events.Insert(ctx, draft); err != nil {
+		t.Fatal(err)
+	}
+	if draft.Status != EventStatusDraft {
+		t.Errorf("new event status = %q, want %q", draft.Status, EventStatusDraft)
+	}
+
+	published := insertEvent(t, m, owner, nil)
+
+	events, err := m.Events.GetAll(ctx, EventFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventIDs(events); !slices.Equal(got, []int{published.ID}) {
+		t.Errorf("GetAll = %v, want only the published event %d", got, published.ID)
+	}
+
+	got, err := m.Events.Get(ctx, draft.ID)
+	if err != nil || got == nil || got.Name != "Draft" {
+		t.Errorf("Get of a draft = %v, %v", got, err)
+	}
+
+	got.Name = "Changed outside"
+	again, _ := m.Events.Get(ctx, draft.ID)
+	if again.Name != "Draft" {
+		t.Error("changing a returned event changed the stored event")
+	}
+
+	update := *again
+	update.Name = "Renamed"
+	update.Tags = []string{" Go ", "go", "Meetup"}
+	if err := m.Events.Update(ctx, &update, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(update.Tags, []string{"go", "meetup"}) {
+		t.Errorf("Update tags = %v, want normalized tags", update.Tags)
+	}
+
+	updated, _ := m.Events.Get(ctx, draft.ID)
+	if updated.Name != "Renamed" || !slices.Equal(updated.Tags, []string{"go", "meetup"}) {
+		t.Errorf("Get after Update = %+v", updated)
+	}
+
+	if _, err := m.Events.Get(ctx, published.ID+100); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing event: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testEventFilters(t *testing.T, m Models) {
+	ctx := context.Background()
+	owner := insertUser(t, m, "owner@example.com")
+	technology := 1
+	music := 3
+
+	goMeetup := insertEvent(t, m, owner, func(e *Event) {
+		e.CategoryID = &technology
+		e.Tags = []string{"go", "backend"}
+	})
+	rustMeetup := insertEvent(t, m, owner, func(e *Event) {
+		e.CategoryID = &technology
+		e.Tags = []string{"rust", "backend"}
+	})
+	concert := insertEvent(t, m, owner, func(e *Event) {
+		e.CategoryID = &music
+		e.Tags = []string{"jazz"}
+	})
+
+	tests := []struct {
+		filter EventFilter
+		want   []int
+	}{
+		{EventFilter{Category: "technology"}, []int{goMeetup.ID, rustMeetup.ID}},
+		{EventFilter{Category: "music"}, []int{concert.ID}},
+		{EventFilter{Category: "unknown"}, []int{}},
+		{EventFilter{Tags: []string{"Backend"}}, []int{goMeetup.ID, rustMeetup.ID}},
+		{EventFilter{Tags: []string{"backend", "go"}}, []int{goMeetup.ID}},
+		{EventFilter{Category: "music", Tags: []string{"backend"}}, []int{}},
+	}
+
+	for _, test := range tests {
+		events, err := m.Events.GetAll(ctx, test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := eventIDs(events); !slices.Equal(got, test.want) {
+			t.Errorf("GetAll(%+v) = %v, want %v", test.filter, got, test.want)
+		}
+	}
+
+	facets, err := m.Events.Facets(ctx, EventFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var categories []string
+	for _, facet := range facets.Categories {
+		categories = append(categories, facet.Slug)
+		if facet.Slug == "technology" && facet.Count != 2 {
+			t.Errorf("technology count = %d, want 2", facet.Count)
+		}
+	}
+	if !slices.Equal(categories, []string{"technology", "music"}) {
+		t.Errorf("category facets = %v, want technology before music", categories)
+	}
+
+	var tags []string
+	for _, facet := range facets.Tags {
+		tags = append(tags, facet.Name)
+	}
+	if !slices.Equal(tags, []string{"backend", "go", "jazz", "rust"}) {
+		t.Errorf("tag facets = %v", tags)
+	}
+}
+
+func testTrash(t *testing.T, m Models) {
+	ctx := context.Background()
+	owner := insertUser(t, m, "owner@example.com")
+	first := insertEvent(t, m, owner, nil)
+	second := insertEvent(t, m, owner, nil)
+
+	if err := m.Events.Delete(ctx, first.ID, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Events.Get(ctx, first.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a trashed event: err = %v, want ErrNotFound", err)
+	}
+	deleted, err := m.Events.GetDeleted(ctx, first.ID)
+	if err != nil || deleted == nil || deleted.DeletedAt == nil {
+		t.Fatalf("GetDeleted = %v, %v", deleted, err)
+	}
+	if _, err := m.Events.GetDeleted(ctx, second.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetDeleted of a live event: err = %v, want ErrNotFound", err)
+	}
+	if err := m.Events.Delete(ctx, first.ID, owner.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting a trashed event: err = %v, want ErrNotFound", err)
+	}
+	if err := m.Events.Update(ctx, deleted, owner.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("updating a trashed event: err = %v, want ErrNotFound", err)
+	}
+
+	trash, err := m.Events.GetTrashByOwner(ctx, owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventIDs(trash); !slices.Equal(got, []int{first.ID}) {
+		t.Errorf("GetTrashByOwner = %v, want %v", got, []int{first.ID})
+	}
+
+	if err := m.Events.Restore(ctx, first.ID, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := m.Events.Get(ctx, first.ID); got == nil || got.DeletedAt != nil {
+		t.Errorf("Get after Restore = %v", got)
+	}
+
+	// Purging only removes events that are in the trash.
+	if err := m.Events.Purge(ctx, second.ID, owner.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("purging a live event: err = %v, want ErrNotFound", err)
+	}
+	if err := m.Events.Restore(ctx, second.ID, owner.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restoring a live event: err = %v, want ErrNotFound", err)
+	}
+	if got, _ := m.Events.Get(ctx, second.ID); got == nil {
+		t.Error("Purge removed a live event")
+	}
+
+	if err := m.Events.Delete(ctx, second.ID, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := m.Events.PurgeDeletedBefore(ctx, time.Now().Add(-time.Hour))
+	if err != nil || purged != 0 {
+		t.Errorf("PurgeDeletedBefore an hour ago = %d, %v, want 0", purged, err)
+	}
+
+	purged, err = m.Events.PurgeDeletedBefore(ctx, time.Now().Add(time.Hour))
+	if err != nil || purged != 1 {
+		t.Errorf("PurgeDeletedBefore an hour from now = %d, %v, want 1", purged, err)
+	}
+	if _, err := m.Events.GetDeleted(ctx, second.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetDeleted of a purged event: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testRevisions(t *testing.T, m Models) {
+	ctx := context.Background()
+	owner := insertUser(t, m, "owner@example.com")
+	event := insertEvent(t, m, owner, nil)
+
+	original := *event
+	event.Name = "Renamed meetup"
+	if err := m.Events.Update(ctx, event, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := m.Revisions.GetByEvent(ctx, event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actions []string
+	for _, revision := range revisions {
+		actions = append(actions, revision.Action)
+	}
+	if want := []string{RevisionUpdated, RevisionPublished, RevisionCreated}; !slices.Equal(actions, want) {
+		t.Fatalf("revision actions = %v, want %v", actions, want)
+	}
+
+	update := revisions[0]
+	if change := update.Changes["name"]; change.From != original.Name || change.To != "Renamed meetup" {
+		t.Errorf("update changes = %+v", update.Changes)
+	}
+	if update.ActorID != owner.ID || update.Snapshot == nil || update.Snapshot.Name != "Renamed meetup" {
+		t.Errorf("update revision = %+v", update)
+	}
+
+	created := revisions[2]
+	got, err := m.Revisions.Get(ctx, created.ID)
+	if err != nil || got == nil || got.Action != RevisionCreated {
+		t.Errorf("Get = %v, %v", got, err)
+	}
+
+	reverted, err := m.Events.Revert(ctx, event.ID, created.ID, owner.ID)
+	if err != nil || reverted == nil {
+		t.Fatalf("Revert = %v, %v", reverted, err)
+	}
+	if reverted.Name != original.Name || reverted.Status != EventStatusPublished {
+		t.Errorf("reverted event = %+v, want the original name and the current status", reverted)
+	}
+
+	other := insertEvent(t, m, owner, nil)
+	if _, err := m.Events.Revert(ctx, other.ID, created.ID, owner.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Revert with another event's revision: err = %v, want ErrNotFound", err)
+	}
+
+	if _, err := m.Revisions.Get(ctx, 1000); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing revision: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testLifecycle(t *testing.T, m Models) {
+	ctx := context.Background()
+	owner := insertUser(t, m, "owner@example.com")
+
+	event := &Event{OwnerID: owner.ID, Name: "Launch", Description: "Product launch party", Date: "2030-01-01", Location: "Tashkent"}
+	if err := m.Events.Insert(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := m.Events.Transition(ctx, event.ID, owner.ID, StatusChange{Status: EventStatusCompleted})
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("completing a draft: err = %v, want ErrInvalidTransition", err)
+	}
+
+	_, err = m.Events.Transition(ctx, 1000, owner.ID, StatusChange{Status: EventStatusPublished})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("publishing a missing event: err = %v, want ErrNotFound", err)
+	}
+
+	publishAt := time.Now().Add(-time.Minute)
+	scheduled, err := m.Events.Transition(ctx, event.ID, owner.ID, StatusChange{Status: EventStatusScheduled, PublishAt: &publishAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scheduled.Status != EventStatusScheduled || scheduled.PublishAt == nil {
+		t.Errorf("scheduled event = %+v", scheduled)
+	}
+
+	published, err := m.Events.PublishDue(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventIDs(published); !slices.Equal(got, []int{event.ID}) {
+		t.Errorf("PublishDue = %v, want %v", got, []int{event.ID})
+	}
+
+	cancelled, err := m.Events.Transition(ctx, event.ID, owner.ID, StatusChange{Status: EventStatusCancelled, CancelReason: "Venue closed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.CancelReason == nil || *cancelled.CancelReason != "Venue closed" {
+		t.Errorf("cancelled event = %+v", cancelled)
+	}
+
+	_, err = m.Events.Transition(ctx, event.ID, owner.ID, StatusChange{Status: EventStatusPublished})
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("publishing a cancelled event: err = %v, want ErrInvalidTransition", err)
+	}
+
+	// Cancelled events stay listed with their reason.
+	events, _ := m.Events.GetAll(ctx, EventFilter{})
+	if got := eventIDs(events); !slices.Equal(got, []int{event.ID}) {
+		t.Errorf("GetAll = %v, want the cancelled event", got)
+	}
+}
+
+func testAttendees(t *testing.T, m Models) {
+	ctx := context.Background()
+	owner := insertUser(t, m, "owner@example.com")
+	guest := insertUser(t, m, "guest@example.com")
+	event := insertEvent(t, m, owner, nil)
+
+	if users, err := m.Attendees.GetAttendeesByEvent(ctx, event.ID); err != nil || len(users) != 0 {
+		t.Errorf("GetAttendeesByEvent before RSVP = %v, %v", users, err)
+	}
+
+	attendee, err := m.Attendees.Insert(ctx, &Attendee{EventID: event.ID, UserID: guest.ID}, guest.ID)
+	if err != nil || attendee.ID == 0 {
+		t.Fatalf("Insert = %v, %v", attendee, err)
+	}
+
+	got, err := m.Attendees.GetByEventAndAttendee(ctx, event.ID, guest.ID)
+	if err != nil || got == nil || got.ID != attendee.ID {
+		t.Errorf("GetByEventAndAttendee = %v, %v", got, err)
+	}
+	if _, err := m.Attendees.GetByEventAndAttendee(ctx, event.ID, owner.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByEventAndAttendee of a non-attendee: err = %v, want ErrNotFound", err)
+	}
+
+	users, err := m.Attendees.GetAttendeesByEvent(ctx, event.ID)
+	if err != nil || len(users) != 1 || users[0].ID != guest.ID || users[0].Email != guest.Email || users[0].Password != "" {
+		t.Errorf("GetAttendeesByEvent = %v, %v", users, err)
+	}
+
+	events, err := m.Events.GetByAttendee(ctx, guest.ID, guest)
+	if err != nil || !slices.Equal(eventIDs(events), []int{event.ID}) {
+		t.Errorf("GetByAttendee = %v, %v", events, err)
+	}
+
+	if err := m.Attendees.Delete(ctx, event.ID, guest.ID, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Attendees.GetByEventAndAttendee(ctx, event.ID, guest.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByEventAndAttendee after Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := m.Attendees.Delete(ctx, event.ID, guest.ID, owner.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting a missing attendee: err = %v, want ErrNotFound", err)
+	}
+
+	revisions, _ := m.Revisions.GetByEvent(ctx, event.ID)
+	if len(revisions) < 2 || revisions[0].Action != RevisionAttendeeRemoved || revisions[1].Action != RevisionAttendeeAdded {
+		t.Fatalf("latest revisions = %+v", revisions)
+	}
+	// Changes are stored as JSON, so numbers come back as float64.
+	if change := revisions[1].Changes["attendee"]; change.From != nil || change.To != float64(guest.ID) {
+		t.Errorf("attendee added change = %+v", change)
+	}
+}
+
+// testEventsByAttendee checks that the events of an attendee only include
+// the drafts and scheduled events the viewer may see.
+func testEventsByAttendee(t *testing.T, m Models) {
+	ctx := context.Background()
+	owner := insertUser(t, m, "owner@example.com")
+	guest := insertUser(t, m, "guest@example.com")
+	other := insertUser(t, m, "other@example.com")
+	admin := insertUser(t, m, "admin@example.com")
+	if err := m.Users.SetAdmin(ctx, admin.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	admin.IsAdmin = true
+
+	published := insertEvent(t, m, owner, nil)
+
+	draft := &Event{OwnerID: owner.ID, Name: "Draft", Description: "Not listed yet", Date: "2030-02-01", Location: "Tashkent"}
+	if err := m.Events.Insert(ctx, draft); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, event := range []*Event{published, draft} {
+		if _, err := m.Attendees.Insert(ctx, &Attendee{EventID: event.ID, UserID: guest.ID}, owner.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		viewer *User
+		want   []int
+	}{
+		{nil, []int{published.ID}},
+		{guest, []int{published.ID}},
+		{other, []int{published.ID}},
+		{owner, []int{draft.ID, published.ID}},
+		{admin, []int{draft.ID, published.ID}},
+	}
+
+	for _, tt := range tests {
+		events, err := m.Events.GetByAttendee(ctx, guest.ID, tt.viewer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := eventIDs(events); !slices.Equal(got, tt.want) {
+			t.Errorf("GetByAttendee viewed by %+v = %v, want %v", tt.viewer, got, tt.want)
+		}
+	}
+}
+
+func testVenues(t *testing.T, m Models) {
+	ctx := context.Background()
+	owner := insertUser(t, m, "owner@example.com")
+
+	latitude, longitude := 41.3111, 69.2797
+	center := &Venue{Name: "Center", Address: "Amir Temur Square", Latitude: &latitude, Longitude: &longitude}
+	if err := m.Venues.Insert(ctx, center); err != nil {
+		t.Fatal(err)
+	}
+
+	farLatitude, farLongitude := 39.6542, 66.9597
+	far := &Venue{Name: "Away", Address: "Registan", Latitude: &farLatitude, Longitude: &farLongitude}
+	if err := m.Venues.Insert(ctx, far); err != nil {
+		t.Fatal(err)
+	}
+
+	unknown := &Venue{Name: "Unknown", Address: "Somewhere"}
+	if err := m.Venues.Insert(ctx, unknown); err != nil {
+		t.Fatal(err)
+	}
+
+	venues, err := m.Venues.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, venue := range venues {
+		names = append(names, venue.Name)
+	}
+	if !slices.Equal(names, []string{"Away", "Center", "Unknown"}) {
+		t.Errorf("GetAll = %v, want venues sorted by name", names)
+	}
+
+	// Updating a venue never changes its owner.
+	unknown.OwnerID = &owner.ID
+	unknown.Address = "Chorsu Bazaar"
+	if err := m.Venues.Update(ctx, unknown); err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Venues.Get(ctx, unknown.ID)
+	if err != nil || got == nil || got.Address != "Chorsu Bazaar" || got.OwnerID != nil {
+		t.Errorf("Get after Update = %+v, %v", got, err)
+	}
+	if err := m.Venues.Update(ctx, &Venue{ID: 1000, Name: "Gone", Address: "Nowhere"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("updating a missing venue: err = %v, want ErrNotFound", err)
+	}
+	if _, err := m.Venues.Get(ctx, 1000); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing venue: err = %v, want ErrNotFound", err)
+	}
+
+	nearby := insertEvent(t, m, owner, func(e *Event) { e.VenueID = &center.ID })
+	insertEvent(t, m, owner, func(e *Event) { e.VenueID = &far.ID })
+	insertEvent(t, m, owner, func(e *Event) { e.VenueID = &unknown.ID })
+
+	near := &GeoPoint{Latitude: 41.3, Longitude: 69.28}
+	events, err := m.Events.GetAll(ctx, EventFilter{Near: near, RadiusKm: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := eventIDs(events); !slices.Equal(got, []int{nearby.ID}) {
+		t.Fatalf("GetAll near = %v, want %v", got, []int{nearby.ID})
+	}
+	if events[0].DistanceKm == nil || *events[0].DistanceKm > 10 {
+		t.Errorf("distance = %v, want within 10 km", events[0].DistanceKm)
+	}
+
+	facets, err := m.Events.Facets(ctx, EventFilter{Near: near, RadiusKm: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(facets.Categories) != 0 || len(facets.Tags) != 0 {
+		t.Errorf("facets near = %+v, want none", facets)
+	}
+}
+
+func testManyEvents(t *testing.T, m Models) {
+	ctx := context.Background()
+	owner := insertUser(t, m, "owner@example.com")
+	technology := 1
+
+	latitude, longitude := 41.3111, 69.2797
+	venue := &Venue{Name: "Center", Address: "Amir Temur Square", Latitude: &latitude, Longitude: &longitude}
+	if err := m.Venues.Insert(ctx, venue); err != nil {
+		t.Fatal(err)
+	}
+
+	count := maxEventIDs + 1
+	for range count {
+		insertEvent(t, m, owner, func(e *Event) {
+			e.VenueID = &venue.ID
+			e.CategoryID = &technology
+			e.Tags = []string{"go"}
+		})
+	}
+
+	near := &GeoPoint{Latitude: 41.3, Longitude: 69.28}
+	events, err := m.Events.GetAll(ctx, EventFilter{Near: near, RadiusKm: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != count {
+		t.Fatalf("GetAll near returned %d events, want %d", len(events), count)
+	}
+	for _, event := range events {
+		if !slices.Equal(event.Tags, []string{"go"}) || event.DistanceKm == nil {
+			t.Fatalf("event %d has tags %v and distance %v, want go and a distance", event.ID, event.Tags, event.DistanceKm)
+		}
+	}
+
+	facets, err := m.Events.Facets(ctx, EventFilter{Near: near, RadiusKm: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(facets.Categories) != 1 || facets.Categories[0].Slug != "technology" || facets.Categories[0].Count != count {
+		t.Errorf("category facets near = %+v, want %d technology events", facets.Categories, count)
+	}
+	if len(facets.Tags) != 1 || facets.Tags[0].Count != count {
+		t.Errorf("tag facets near = %+v, want %d go events", facets.Tags, count)
+	}
+}
+
+func testCategories(t *testing.T, m Models) {
+	ctx := context.Background()
+
+	category := &Category{Name: "  Food & Drink "}
+	if err := m.Categories.Insert(ctx, category); err != nil {
+		t.Fatal(err)
+	}
+	if category.Name != "Food & Drink" || category.Slug != "food-drink" {
+		t.Errorf("inserted category = %+v", category)
+	}
+
+	if err := m.Categories.Insert(ctx, &Category{Name: "music"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("inserting a category that differs only in case: err = %v, want ErrConflict", err)
+	}
+	if err := m.Categories.Insert(ctx, &Category{Name: "日本語"}); !errors.Is(err, ErrEmptySlug) {
+		t.Errorf("inserting a category without a slug: err = %v, want ErrEmptySlug", err)
+	}
+
+	categories, err := m.Categories.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, category := range categories {
+		names = append(names, category.Name)
+	}
+	want := []string{"Arts", "Business", "Community", "Food & Drink", "Music", "Sports", "Technology"}
+	if !slices.Equal(names, want) {
+		t.Errorf("GetAll = %v, want %v", names, want)
+	}
+
+	got, err := m.Categories.Get(ctx, category.ID)
+	if err != nil || got == nil || got.Slug != "food-drink" {
+		t.Errorf("Get = %v, %v", got, err)
+	}
+	if _, err := m.Categories.Get(ctx, 1000); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing category: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testTransactions(t *testing.T, m Models) {
+	ctx := context.Background()
+	owner := insertUser(t, m, "owner@example.com")
+	event := insertEvent(t, m, owner, nil)
+
+	errRollback := errors.New("roll back")
+
+	err := m.WithTx(ctx, func(tx Models) error {
+		if err := tx.Users.Insert(ctx, &User{Email: "kept@example.com", Password: "hash"}); err != nil {
+			return err
+		}
+
+		// A nested transaction that fails is undone on its own.
+		nested := tx.WithTx(ctx, func(tx Models) error {
+			if err := tx.Users.Insert(ctx, &User{Email: "undone@example.com", Password: "hash"}); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(nested, errRollback) {
+			t.Errorf("nested WithTx: err = %v, want the error of the function", nested)
+		}
+
+		_, err := tx.Users.GetByEmail(ctx, "kept@example.com")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx = %v", err)
+	}
+	if _, err := m.Users.GetByEmail(ctx, "kept@example.com"); err != nil {
+		t.Errorf("GetByEmail of a committed user: err = %v", err)
+	}
+	if _, err := m.Users.GetByEmail(ctx, "undone@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByEmail of a user inserted in a failed nested transaction: err = %v, want ErrNotFound", err)
+	}
+
+	// Methods that run their own transaction join the outer one.
+	err = m.WithTx(ctx, func(tx Models) error {
+		if _, err := tx.Attendees.Insert(ctx, &Attendee{EventID: event.ID, UserID: owner.ID}, owner.ID); err != nil {
+			return err
+		}
+		if err := tx.Events.Delete(ctx, event.ID, owner.ID); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithTx: err = %v, want the error of the function", err)
+	}
+	if _, err := m.Events.Get(ctx, event.ID); err != nil {
+		t.Errorf("Get of an event deleted in a rolled back transaction: err = %v", err)
+	}
+	if _, err := m.Attendees.GetByEventAndAttendee(ctx, event.ID, owner.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByEventAndAttendee after rollback: err = %v, want ErrNotFound", err)
+	}
+	if revisions, _ := m.Revisions.GetByEvent(ctx, event.ID); len(revisions) != 2 {
+		t.Errorf("revisions after rollback = %+v, want created and published", revisions)
+	}
+
+	// Concurrent check-then-insert transactions add the attendee once.
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := m.WithTx(ctx, func(tx Models) error {
+				_, err := tx.Attendees.GetByEventAndAttendee(ctx, event.ID, owner.ID)
+				if !errors.Is(err, ErrNotFound) {
+					return err
+				}
+				if _, err := tx.Attendees.Insert(ctx, &Attendee{EventID: event.ID, UserID: owner.ID}, owner.ID); err != nil {
+					return err
+				}
+				return nil
+			})
+			if err != nil {
+				t.Errorf("concurrent WithTx = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if users, err := m.Attendees.GetAttendeesByEvent(ctx, event.ID); err != nil || len(users) != 1 {
+		t.Errorf("attendees after concurrent inserts = %v, %v, want one", users, err)
+	}
+}
+
+func testCanceledContext(t *testing.T, m Models) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := m.Events.GetAll(ctx, EventFilter{}); !errors.Is(err, ErrCanceled) {
+		t.Errorf("GetAll with a canceled context: err = %v, want ErrCanceled", err)
+	}
+	if err := m.Users.Insert(ctx, &User{Email: "late@example.com"}); !errors.Is(err, ErrCanceled) {
+		t.Errorf("Insert with a canceled context: err = %v, want ErrCanceled", err)
+	}
+}
@@ -0,0 +1,216 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+type RevisionModel struct {
+	model
+}
+
+// Revision is an immutable record of a single change made to an event.
+// Snapshot holds the state of the event right after the change.
+type Revision struct {
+	ID        int                    `json:"id"`
+	EventID   int                    `json:"eventId"`
+	ActorID   int                    `json:"actorId"`
+	Action    string                 `json:"action"`
+	Changes   map[string]FieldChange `json:"changes"`
+	Snapshot  *Event                 `json:"snapshot"`
+	CreatedAt time.Time              `json:"createdAt"`
+}
+
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// SystemActorID is the actor recorded for changes that are not made by a
+// user, such as purging expired trash.
+const SystemActorID = 0
+
+const (
+	RevisionCreated         = "create"
+	RevisionUpdated         = "update"
+	RevisionDeleted         = "delete"
+	RevisionRestored        = "restore"
+	RevisionPurged          = "purge"
+	RevisionReverted        = "revert"
+	RevisionAttendeeAdded   = "attendee_added"
+	RevisionAttendeeRemoved = "attendee_removed"
+)
+
+func (m *RevisionModel) GetByEvent(ctx context.Context, eventID int) (_ []*Revision, err error) {
+	ctx, done := m.instrument(ctx, "revision", "GetByEvent", m.timeouts.Read)
+	defer done(&err)
+
+	query := `
+		SELECT id, event_id, actor_id, action, changes, snapshot, created_at
+		FROM event_revisions
+		WHERE event_id = $1
+		ORDER BY id DESC
+	`
+
+	rows, err := m.readDB.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	revisions := []*Revision{}
+
+	for rows.Next() {
+		var revision Revision
+		if err := scanRevision(rows, &revision); err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (m *RevisionModel) Get(ctx context.Context, id int) (_ *Revision, err error) {
+	ctx, done := m.instrument(ctx, "revision", "Get", m.timeouts.Read)
+	defer done(&err)
+
+	return getRevision(ctx, m.readDB, id)
+}
+
+func getRevision(ctx context.Context, db querier, id int) (*Revision, error) {
+	query := `
+		SELECT id, event_id, actor_id, action, changes, snapshot, created_at
+		FROM event_revisions
+		WHERE id = $1
+	`
+
+	var revision Revision
+	err := scanRevision(db.QueryRowContext(ctx, query, id), &revision)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("revision")
+		}
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+func scanRevision(row rowScanner, revision *Revision) error {
+	var changes, snapshot string
+
+	err := row.Scan(
+		&revision.ID,
+		&revision.EventID,
+		&revision.ActorID,
+		&revision.Action,
+		&changes,
+		&snapshot,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal([]byte(changes), &revision.Changes); err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(snapshot), &revision.Snapshot)
+}
+
+// insertRevision records a revision as part of the transaction that made the
+// change, so that a change is never stored without its audit record.
+func insertRevision(ctx context.Context, tx querier, revision *Revision) error {
+	if revision.Changes == nil {
+		revision.Changes = map[string]FieldChange{}
+	}
+
+	changes, err := json.Marshal(revision.Changes)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := json.Marshal(revision.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO event_revisions (event_id, actor_id, action, changes, snapshot)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at
+	`
+
+	return tx.QueryRowContext(
+		ctx,
+		query,
+		revision.EventID,
+		revision.ActorID,
+		revision.Action,
+		string(changes),
+		string(snapshot),
+	).Scan(
+		&revision.ID,
+		&revision.CreatedAt,
+	)
+}
+
+// diffEvents returns the fields whose values differ between two states of an
+// event, keyed by their JSON names. A nil state counts as having no fields.
+func diffEvents(before, after *Event) (map[string]FieldChange, error) {
+	from, err := eventFields(before)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := eventFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]FieldChange{}
+
+	for field, value := range to {
+		if !reflect.DeepEqual(from[field], value) {
+			changes[field] = FieldChange{From: from[field], To: value}
+		}
+	}
+
+	for field, value := range from {
+		if _, ok := to[field]; !ok {
+			changes[field] = FieldChange{From: value, To: nil}
+		}
+	}
+
+	delete(changes, "id")
+
+	return changes, nil
+}
+
+func eventFields(event *Event) (map[string]any, error) {
+	fields := map[string]any{}
+	if event == nil {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
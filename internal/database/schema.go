@@ -0,0 +1,41 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SchemaVersion is the migration version this build of the application
+// expects the database to be at. Bump it together with every new migration.
+const SchemaVersion = 10
+
+// CurrentSchemaVersion returns the version recorded by the migration tool and
+// whether the last migration failed halfway.
+func CurrentSchemaVersion(ctx context.Context, db *sql.DB) (version int, dirty bool, err error) {
+	err = db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+
+	return version, dirty, err
+}
+
+// CheckSchemaVersion returns an error unless the database schema is exactly
+// at SchemaVersion and not dirty.
+func CheckSchemaVersion(ctx context.Context, db *sql.DB) error {
+	version, dirty, err := CurrentSchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("schema version %d is dirty", version)
+	}
+
+	if version != SchemaVersion {
+		return fmt.Errorf("schema version is %d, expected %d", version, SchemaVersion)
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"errors"
+	"io/fs"
+	"rest-api-go-gin/internal/database/migrations"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+func TestSchemaVersionIsLastMigration(t *testing.T) {
+	for _, dialect := range []Dialect{SQLite, Postgres} {
+		src, err := iofs.New(migrations.FS, string(dialect))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		last, err := src.First()
+		for err == nil {
+			var next uint
+			if next, err = src.Next(last); err == nil {
+				last = next
+			}
+		}
+		src.Close()
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Fatal(err)
+		}
+
+		if last != SchemaVersion {
+			t.Errorf("the last %s migration is %d, but SchemaVersion is %d", dialect, last, SchemaVersion)
+		}
+	}
+}
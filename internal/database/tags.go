@@ -0,0 +1,318 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// EventFilter narrows down the publicly listed events. Category is matched
+// against the category slug and every tag in Tags must be present. If Near is
+// set only events at venues within RadiusKm of it are kept.
+type EventFilter struct {
+	Category string
+	Tags     []string
+	Near     *GeoPoint
+	RadiusKm float64
+}
+
+type Facets struct {
+	Categories []*CategoryFacet `json:"categories"`
+	Tags       []*TagFacet      `json:"tags"`
+}
+
+type CategoryFacet struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Slug  string `json:"slug"`
+	Count int    `json:"count"`
+}
+
+type TagFacet struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// NormalizeTags lowercases and trims tag names, collapses inner whitespace
+// and drops empty and duplicate tags while keeping the original order.
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
+
+// Facets returns how many publicly listed events matching the filter fall
+// into each category and carry each tag.
+func (m *EventModel) Facets(ctx context.Context, filter EventFilter) (_ *Facets, err error) {
+	ctx, done := m.instrument(ctx, "event", "Facets", m.timeouts.Read)
+	defer done(&err)
+
+	facets := &Facets{Categories: []*CategoryFacet{}, Tags: []*TagFacet{}}
+
+	// Distances are computed outside of the database, so count the events in
+	// range once they are resolved.
+	if filter.Near != nil {
+		events, err := m.getAll(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		categories, err := m.categories(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return countFacets(events, categories), nil
+	}
+
+	where, args := filter.where()
+
+	query := `
+		SELECT c.id, c.name, c.slug, COUNT(e.id) FROM events e
+		JOIN categories c ON c.id = e.category_id
+		WHERE ` + where + `
+		GROUP BY c.id, c.name, c.slug
+		ORDER BY COUNT(e.id) DESC, LOWER(c.name), c.name
+	`
+
+	rows, err := m.readDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var facet CategoryFacet
+		if err := rows.Scan(&facet.ID, &facet.Name, &facet.Slug, &facet.Count); err != nil {
+			return nil, err
+		}
+
+		facets.Categories = append(facets.Categories, &facet)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT t.name, COUNT(e.id) FROM events e
+		JOIN event_tags et ON et.event_id = e.id
+		JOIN tags t ON t.id = et.tag_id
+		WHERE ` + where + `
+		GROUP BY t.name
+		ORDER BY COUNT(e.id) DESC, t.name
+	`
+
+	tagRows, err := m.readDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tagRows.Close()
+
+	for tagRows.Next() {
+		var facet TagFacet
+		if err := tagRows.Scan(&facet.Name, &facet.Count); err != nil {
+			return nil, err
+		}
+
+		facets.Tags = append(facets.Tags, &facet)
+	}
+
+	if err = tagRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+// categories returns every category by its id.
+func (m *EventModel) categories(ctx context.Context) (map[int]*Category, error) {
+	rows, err := m.readDB.QueryContext(ctx, `SELECT id, name, slug FROM categories`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	categories := map[int]*Category{}
+
+	for rows.Next() {
+		var category Category
+		if err := rows.Scan(&category.ID, &category.Name, &category.Slug); err != nil {
+			return nil, err
+		}
+
+		categories[category.ID] = &category
+	}
+
+	return categories, rows.Err()
+}
+
+// countFacets counts the events in each of the categories and with each tag,
+// ordered like the facets counted by the database.
+func countFacets(events []*Event, categories map[int]*Category) *Facets {
+	categoryCounts := map[int]int{}
+	tagCounts := map[string]int{}
+
+	for _, event := range events {
+		if event.CategoryID != nil {
+			if _, ok := categories[*event.CategoryID]; ok {
+				categoryCounts[*event.CategoryID]++
+			}
+		}
+		for _, tag := range event.Tags {
+			tagCounts[tag]++
+		}
+	}
+
+	facets := &Facets{Categories: []*CategoryFacet{}, Tags: []*TagFacet{}}
+
+	for id, count := range categoryCounts {
+		category := categories[id]
+		facets.Categories = append(facets.Categories, &CategoryFacet{
+			ID:    category.ID,
+			Name:  category.Name,
+			Slug:  category.Slug,
+			Count: count,
+		})
+	}
+
+	for name, count := range tagCounts {
+		facets.Tags = append(facets.Tags, &TagFacet{Name: name, Count: count})
+	}
+
+	slices.SortFunc(facets.Categories, func(a, b *CategoryFacet) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), compareNoCase(a.Name, b.Name))
+	})
+	slices.SortFunc(facets.Tags, func(a, b *TagFacet) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), compareNoCase(a.Name, b.Name))
+	})
+
+	return facets
+}
+
+// where builds the WHERE clause shared by the event listing and its facets.
+func (f EventFilter) where() (string, []any) {
+	conditions := []string{
+		`e.deleted_at IS NULL`,
+		publicCondition,
+	}
+	var args []any
+
+	if f.Category != "" {
+		args = append(args, f.Category)
+		conditions = append(conditions, fmt.Sprintf(
+			`e.category_id = (SELECT id FROM categories WHERE slug = $%d)`, len(args),
+		))
+	}
+
+	for _, tag := range NormalizeTags(f.Tags) {
+		args = append(args, tag)
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM event_tags et JOIN tags t ON t.id = et.tag_id
+			WHERE et.event_id = e.id AND t.name = $%d
+		)`, len(args)))
+	}
+
+	if f.Near != nil {
+		var box string
+		box, args = boundingBoxCondition(*f.Near, f.RadiusKm, args)
+		conditions = append(conditions, `e.venue_id IN (SELECT v.id FROM venues v WHERE `+box+`)`)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// setEventTags replaces the tags of an event, creating tags that do not
+// exist yet.
+func setEventTags(ctx context.Context, q querier, eventID int, tags []string) error {
+	if _, err := q.ExecContext(ctx, `DELETE FROM event_tags WHERE event_id = $1`, eventID); err != nil {
+		return err
+	}
+
+	for _, tag := range NormalizeTags(tags) {
+		query := `INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`
+		if _, err := q.ExecContext(ctx, query, tag); err != nil {
+			return err
+		}
+
+		query = `INSERT INTO event_tags (event_id, tag_id) SELECT CAST($1 AS INTEGER), id FROM tags WHERE name = $2`
+		if _, err := q.ExecContext(ctx, query, eventID, tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// maxEventIDs bounds the event ids listed in a single query, far below the
+// bound parameter limits of SQLite and PostgreSQL.
+const maxEventIDs = 500
+
+// loadEventTags fills in the tags of the given events.
+func loadEventTags(ctx context.Context, q querier, events ...*Event) error {
+	for chunk := range slices.Chunk(events, maxEventIDs) {
+		if err := loadTagsOf(ctx, q, chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadTagsOf fills in the tags of at most maxEventIDs events.
+func loadTagsOf(ctx context.Context, q querier, events []*Event) error {
+	byID := make(map[int]*Event, len(events))
+	placeholders := make([]string, 0, len(events))
+	args := make([]any, 0, len(events))
+
+	for _, event := range events {
+		event.Tags = []string{}
+		byID[event.ID] = event
+		args = append(args, event.ID)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+
+	query := `
+		SELECT et.event_id, t.name FROM event_tags et
+		JOIN tags t ON t.id = et.tag_id
+		WHERE et.event_id IN (` + strings.Join(placeholders, ", ") + `)
+		ORDER BY t.name
+	`
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventID int
+		var tag string
+		if err := rows.Scan(&eventID, &tag); err != nil {
+			return err
+		}
+
+		if event, ok := byID[eventID]; ok {
+			event.Tags = append(event.Tags, tag)
+		}
+	}
+
+	return rows.Err()
+}
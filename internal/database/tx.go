@@ -0,0 +1,176 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// txAttempts is how many times WithTx runs a transaction that keeps failing
+// with a retryable error, and txRetryDelay how long it waits after the first
+// failure. The wait grows with every attempt and is jittered, so that the
+// transactions that collided do not collide again.
+const (
+	txAttempts   = 3
+	txRetryDelay = 20 * time.Millisecond
+)
+
+// conn runs the statements of the SQL models.
+type conn interface {
+	querier
+	// begin starts a transaction, or a savepoint if conn is a transaction
+	// already, so that model methods that need a transaction of their own
+	// also work within WithTx.
+	begin(ctx context.Context) (tx, error)
+}
+
+// tx is a transaction started by begin. Like with *sql.Tx, Rollback after
+// Commit only returns sql.ErrTxDone, so it can be deferred.
+type tx interface {
+	conn
+	Commit() error
+	Rollback() error
+}
+
+// dbConn runs statements on the connection pool.
+type dbConn struct {
+	*sql.DB
+}
+
+func (c dbConn) begin(ctx context.Context) (tx, error) {
+	return c.beginTx(ctx, nil)
+}
+
+func (c dbConn) beginTx(ctx context.Context, opts *sql.TxOptions) (tx, error) {
+	t, err := c.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &txConn{Tx: t, savepoints: new(int)}, nil
+}
+
+// txConn runs statements in a transaction.
+type txConn struct {
+	*sql.Tx
+	// savepoints counts the savepoints started in the transaction, to name
+	// them.
+	savepoints *int
+}
+
+func (c *txConn) begin(ctx context.Context) (tx, error) {
+	*c.savepoints++
+	name := fmt.Sprintf("sp%d", *c.savepoints)
+
+	if _, err := c.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, err
+	}
+
+	return &savepoint{txConn: c, ctx: ctx, name: name}, nil
+}
+
+// savepoint is a transaction nested in a txConn. Committing it keeps its
+// changes as part of the outer transaction and rolling it back undoes only
+// them.
+type savepoint struct {
+	*txConn
+	ctx  context.Context
+	name string
+	done bool
+}
+
+func (s *savepoint) Commit() error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+
+	_, err := s.ExecContext(s.ctx, "RELEASE SAVEPOINT "+s.name)
+	return err
+}
+
+func (s *savepoint) Rollback() error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+
+	// Rolling back to a savepoint keeps it, so it is released as well.
+	if _, err := s.ExecContext(s.ctx, "ROLLBACK TO SAVEPOINT "+s.name); err != nil {
+		return err
+	}
+	_, err := s.ExecContext(s.ctx, "RELEASE SAVEPOINT "+s.name)
+	return err
+}
+
+// withTx implements Models.WithTx for the SQL models. On PostgreSQL the
+// transaction is serializable, so that a row fn checks for cannot be inserted
+// by a concurrent transaction before fn's own write; SQLite transactions are
+// serializable anyway.
+func (m model) withTx(ctx context.Context, fn func(tx Models) error) (err error) {
+	ctx, span := tracer.Start(ctx, "Models.WithTx",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attribute.String("db.system", string(m.dialect))),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	db, ok := m.db.(dbConn)
+	if !ok {
+		// Nested in another WithTx, which retries if needed.
+		return m.runTx(ctx, m.db.begin, fn)
+	}
+
+	begin := db.begin
+	if m.dialect == Postgres {
+		begin = func(ctx context.Context) (tx, error) {
+			return db.beginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = m.runTx(ctx, begin, fn)
+		if err == nil || attempt == txAttempts || !retryable(err) {
+			return err
+		}
+
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt), attribute.String("error", err.Error())))
+
+		delay := time.Duration(attempt)*txRetryDelay + rand.N(txRetryDelay)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// runTx runs fn once in a transaction started by begin.
+func (m model) runTx(ctx context.Context, begin func(context.Context) (tx, error), fn func(tx Models) error) error {
+	t, err := begin(ctx)
+	if err != nil {
+		return translateError(ctx, err)
+	}
+	defer t.Rollback()
+
+	txModel := m
+	txModel.db = t
+	txModel.readDB = t
+
+	if err := fn(txModel.models()); err != nil {
+		return err
+	}
+
+	return translateError(ctx, t.Commit())
+}
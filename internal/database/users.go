@@ -3,11 +3,10 @@ package database
 import (
 	"context"
 	"database/sql"
-	"time"
 )
 
 type UserModel struct {
-	DB *sql.DB
+	model
 }
 
 type User struct {
@@ -15,52 +14,80 @@ type User struct {
 	Email    string `json:"email"`
 	Name     string `json:"name"`
 	Password string `json:"-"`
+	// IsAdmin grants access to the administration endpoints.
+	IsAdmin bool `json:"-"`
 }
 
-func (m *UserModel) Insert(user *User) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+func (m *UserModel) Insert(ctx context.Context, user *User) (err error) {
+	ctx, done := m.instrument(ctx, "user", "Insert", m.timeouts.Write)
+	defer done(&err)
 
-	query := `INSERT INTO users (email, password, name) VALUES ($1, $2, $3) RETURNING id`
+	query := `INSERT INTO users (email, password, name, is_admin) VALUES ($1, $2, $3, $4) RETURNING id`
 
-	return m.DB.QueryRowContext(
+	return m.db.QueryRowContext(
 		ctx,
 		query,
 		user.Email,
 		user.Password,
 		user.Name,
+		user.IsAdmin,
 	).Scan(
 		&user.ID,
 	)
 }
 
-func (m *UserModel) GetByID(userID int) (*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+func (m *UserModel) GetByID(ctx context.Context, userID int) (_ *User, err error) {
+	ctx, done := m.instrument(ctx, "user", "GetByID", m.timeouts.Read)
+	defer done(&err)
 
-	query := `SELECT id, name, email, password FROM users WHERE id = $1`
+	query := `SELECT id, email, name, password, is_admin FROM users WHERE id = $1`
 
 	return m.getUser(query, ctx, userID)
 }
 
-func (m *UserModel) GetByEmail(email string) (*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+func (m *UserModel) GetByEmail(ctx context.Context, email string) (_ *User, err error) {
+	ctx, done := m.instrument(ctx, "user", "GetByEmail", m.timeouts.Read)
+	defer done(&err)
 
-	query := `SELECT u.id, u.email, u.name, u.password FROM users u WHERE u.email = $1`
+	query := `SELECT u.id, u.email, u.name, u.password, u.is_admin FROM users u WHERE u.email = $1`
 
 	return m.getUser(query, ctx, email)
 }
 
+// SetAdmin grants or revokes administrator access.
+func (m *UserModel) SetAdmin(ctx context.Context, userID int, admin bool) (err error) {
+	ctx, done := m.instrument(ctx, "user", "SetAdmin", m.timeouts.Write)
+	defer done(&err)
+
+	res, err := m.db.ExecContext(ctx, `UPDATE users SET is_admin = $1 WHERE id = $2`, admin, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return notFound("user")
+	}
+
+	return nil
+}
+
 func (m *UserModel) getUser(query string, ctx context.Context, args ...interface{}) (*User, error) {
 	var user User
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+	err := m.readDB.QueryRowContext(ctx, query, args...).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Name,
 		&user.Password,
+		&user.IsAdmin,
 	)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("user")
+		}
 		return nil, err
 	}
 
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+type VenueModel struct {
+	model
+}
+
+// Venue is a place where events happen. Venues migrated from free-text event
+// locations have no owner and no coordinates; they are shared by the events
+// of many users, so only administrators may change them.
+type Venue struct {
+	ID                 int      `json:"id"`
+	OwnerID            *int     `json:"ownerId,omitempty"`
+	Name               string   `json:"name" binding:"required,notblank,min=2"`
+	Address            string   `json:"address" binding:"required,notblank,min=3"`
+	Latitude           *float64 `json:"latitude" binding:"required_with=Longitude,omitempty,min=-90,max=90"`
+	Longitude          *float64 `json:"longitude" binding:"required_with=Latitude,omitempty,min=-180,max=180"`
+	Capacity           *int     `json:"capacity,omitempty" binding:"omitempty,min=1"`
+	AccessibilityNotes string   `json:"accessibilityNotes"`
+}
+
+const venueColumns = `id, owner_id, name, address, latitude, longitude, capacity, accessibility_notes`
+
+func (m *VenueModel) Insert(ctx context.Context, venue *Venue) (err error) {
+	ctx, done := m.instrument(ctx, "venue", "Insert", m.timeouts.Write)
+	defer done(&err)
+
+	query := `
+		INSERT INTO venues (owner_id, name, address, latitude, longitude, capacity, accessibility_notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id
+	`
+
+	return m.db.QueryRowContext(
+		ctx,
+		query,
+		venue.OwnerID,
+		venue.Name,
+		venue.Address,
+		venue.Latitude,
+		venue.Longitude,
+		venue.Capacity,
+		venue.AccessibilityNotes,
+	).Scan(&venue.ID)
+}
+
+func (m *VenueModel) GetAll(ctx context.Context) (_ []*Venue, err error) {
+	ctx, done := m.instrument(ctx, "venue", "GetAll", m.timeouts.Read)
+	defer done(&err)
+
+	rows, err := m.readDB.QueryContext(ctx, `SELECT `+venueColumns+` FROM venues ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	venues := []*Venue{}
+
+	for rows.Next() {
+		var venue Venue
+		if err := scanVenue(rows, &venue); err != nil {
+			return nil, err
+		}
+
+		venues = append(venues, &venue)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return venues, nil
+}
+
+func (m *VenueModel) Get(ctx context.Context, id int) (_ *Venue, err error) {
+	ctx, done := m.instrument(ctx, "venue", "Get", m.timeouts.Read)
+	defer done(&err)
+
+	var venue Venue
+
+	err = scanVenue(m.readDB.QueryRowContext(ctx, `SELECT `+venueColumns+` FROM venues WHERE id = $1`, id), &venue)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("venue")
+		}
+		return nil, err
+	}
+
+	return &venue, nil
+}
+
+func (m *VenueModel) Update(ctx context.Context, venue *Venue) (err error) {
+	ctx, done := m.instrument(ctx, "venue", "Update", m.timeouts.Write)
+	defer done(&err)
+
+	query := `
+		UPDATE venues SET name = $1, address = $2, latitude = $3, longitude = $4,
+			capacity = $5, accessibility_notes = $6
+		WHERE id = $7
+	`
+
+	res, err := m.db.ExecContext(
+		ctx,
+		query,
+		venue.Name,
+		venue.Address,
+		venue.Latitude,
+		venue.Longitude,
+		venue.Capacity,
+		venue.AccessibilityNotes,
+		venue.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		return notFound("venue")
+	}
+
+	return nil
+}
+
+func scanVenue(row rowScanner, venue *Venue) error {
+	return row.Scan(
+		&venue.ID,
+		&venue.OwnerID,
+		&venue.Name,
+		&venue.Address,
+		&venue.Latitude,
+		&venue.Longitude,
+		&venue.Capacity,
+		&venue.AccessibilityNotes,
+	)
+}
@@ -1,24 +0,0 @@
-package env
-
-import (
-	"os"
-	"strconv"
-)
-
-func GetEnvString(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
-	}
-
-	return defaultValue
-}
-
-func GetEnvInt(key string, defaultValue int) int {
-	if value, exists := os.LookupEnv(key); exists {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
-	}
-
-	return defaultValue
-}
\ No newline at end of file
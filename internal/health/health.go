@@ -0,0 +1,111 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check reports the health of a single dependency. It should return promptly
+// once ctx is done.
+type Check func(ctx context.Context) error
+
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Registry holds the dependency checks that decide whether the application
+// is ready to serve traffic.
+type Registry struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	names  []string
+	checks map[string]Check
+}
+
+// NewRegistry creates a registry that gives each check at most timeout to
+// complete.
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{
+		timeout: timeout,
+		checks:  map[string]Check{},
+	}
+}
+
+// Register adds a check under the given name, replacing any check that was
+// registered under the same name before.
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.checks[name]; !exists {
+		r.names = append(r.names, name)
+	}
+	r.checks[name] = check
+}
+
+// Run executes all checks concurrently and reports their results in the
+// order they were registered. The report fails if any check fails.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	names := append([]string(nil), r.names...)
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = r.checks[name]
+	}
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make([]Result, len(names))}
+
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = r.run(ctx, names[i], checks[i])
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, name string, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := Result{
+		Name:      name,
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
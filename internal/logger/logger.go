@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys, matched case-insensitively as substrings,
+// whose values are never written to the log.
+var sensitiveKeys = []string{"password", "token", "secret", "authorization", "cookie"}
+
+// New creates a logger writing to w. Level is one of debug, info, warn or
+// error and format is either json or text.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	options := &slog.HandlerOptions{
+		Level:       lvl,
+		ReplaceAttr: redact,
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q, expected json or text", format)
+	}
+}
+
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if isSensitive(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+
+	return attr
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type contextKey struct{}
+
+// WithContext returns a copy of ctx carrying the logger.
+func WithContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx, or the default logger if
+// there is none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+
+	return slog.Default()
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "api"
+
+// Metrics holds the collectors exposed on the metrics endpoint. Each instance
+// has its own registry, so nothing is registered globally.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests  *prometheus.CounterVec
+	httpDuration  *prometheus.HistogramVec
+	queryDuration *prometheus.HistogramVec
+
+	Registrations prometheus.Counter
+	Logins        *prometheus.CounterVec
+	EventsCreated prometheus.Counter
+	RSVPs         prometheus.Counter
+	RateLimited   *prometheus.CounterVec
+}
+
+// New creates the collectors and registers them together with the Go runtime,
+// process and connection pool statistics of db.
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of handled HTTP requests.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time spent handling HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Time spent in model methods.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"model", "method"}),
+		Registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "user_registrations_total",
+			Help:      "Number of registered users.",
+		}),
+		Logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "user_logins_total",
+			Help:      "Number of login attempts by result.",
+		}, []string{"result"}),
+		EventsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_created_total",
+			Help:      "Number of created events.",
+		}),
+		RSVPs: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "event_rsvps_total",
+			Help:      "Number of attendees added to events.",
+		}),
+		RateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limited_requests_total",
+			Help:      "Number of requests rejected by a rate limit, by policy.",
+		}, []string{"policy"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "main"),
+		m.httpRequests,
+		m.httpDuration,
+		m.queryDuration,
+		m.Registrations,
+		m.Logins,
+		m.EventsCreated,
+		m.RSVPs,
+		m.RateLimited,
+	)
+
+	return m
+}
+
+// RegisterDB adds the connection pool statistics of another pool of the
+// database, labelled with name.
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the registered metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveRequest records a handled HTTP request. Route is the route template
+// rather than the path, so that path parameters do not create new series.
+func (m *Metrics) ObserveRequest(method, route, status string, duration time.Duration) {
+	m.httpRequests.WithLabelValues(method, route, status).Inc()
+	m.httpDuration.WithLabelValues(method, route, status).Observe(duration.Seconds())
+}
+
+// ObserveQuery records the duration of a model method. It matches
+// database.QueryObserver.
+func (m *Metrics) ObserveQuery(model, method string, duration time.Duration) {
+	m.queryDuration.WithLabelValues(model, method).Observe(duration.Seconds())
+}
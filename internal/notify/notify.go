@@ -0,0 +1,33 @@
+package notify
+
+import (
+	"context"
+	"rest-api-go-gin/internal/logger"
+)
+
+// Notification is a message addressed to a single user.
+type Notification struct {
+	UserID  int
+	Email   string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers notifications to users.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier writes notifications to the application log instead of
+// delivering them. It is used until a real delivery channel is configured.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, n Notification) error {
+	logger.FromContext(ctx).Info("notify user",
+		"user_id", n.UserID,
+		"email", n.Email,
+		"subject", n.Subject,
+		"body", n.Body,
+	)
+	return nil
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the buckets in memory. Each instance of the API then
+// limits its clients on its own.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		fresh := newBucket(limit, now)
+		b = &fresh
+		s.buckets[key] = b
+	}
+
+	return b.take(limit, now), nil
+}
+
+func (s *MemoryStore) Prune(_ context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, b := range s.buckets {
+		if !b.fullAt.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+
+	return nil
+}
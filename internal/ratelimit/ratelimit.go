@@ -0,0 +1,84 @@
+// Package ratelimit limits how often clients may call the API with token
+// buckets. A bucket holds up to Limit.Requests tokens and is refilled evenly
+// over Limit.Per; every request takes one token and is rejected when none is
+// left. The buckets are kept in a Store, in memory or in the database.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit allows Requests requests per Per, all of which may come at once.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// rate is the number of tokens added to a bucket per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of requests the bucket allows right away.
+	Remaining int
+	// Reset is how long it takes until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long a rejected client has to wait for a token.
+	RetryAfter time.Duration
+}
+
+// Store keeps the token buckets by key. Take must update a bucket atomically,
+// so that concurrent requests cannot spend the same token.
+type Store interface {
+	// Take takes a token from the bucket with the key, which starts full.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+	// Prune removes the buckets that have refilled completely by now. They
+	// are equivalent to buckets that do not exist.
+	Prune(ctx context.Context, now time.Time) error
+}
+
+// bucket is the state of a token bucket. fullAt is when it will be full
+// again if no token is taken, the time after which it can be pruned.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	fullAt  time.Time
+}
+
+func newBucket(limit Limit, now time.Time) bucket {
+	return bucket{tokens: float64(limit.Requests), updated: now, fullAt: now}
+}
+
+// take refills the bucket for the time passed since it was last updated and
+// takes a token from it if there is one.
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	rate := limit.rate()
+	capacity := float64(limit.Requests)
+
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*rate)
+		b.updated = now
+	}
+
+	result := Result{Allowed: b.tokens >= 1}
+	if result.Allowed {
+		b.tokens--
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((capacity - b.tokens) / rate)
+	b.fullAt = now.Add(result.Reset)
+
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
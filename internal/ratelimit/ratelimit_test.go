@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"rest-api-go-gin/internal/database"
+	"sync"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+var limit = Limit{Requests: 3, Per: time.Minute}
+
+func TestBucket(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	b := newBucket(limit, start)
+
+	for i := range 3 {
+		result := b.take(limit, start)
+		if !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf("request %d: %+v", i+1, result)
+		}
+	}
+
+	result := b.take(limit, start)
+	if result.Allowed || result.RetryAfter != 20*time.Second || result.Reset != time.Minute {
+		t.Errorf("request over the limit: %+v", result)
+	}
+
+	// One token is refilled every 20 seconds.
+	result = b.take(limit, start.Add(30*time.Second))
+	if !result.Allowed || result.Remaining != 0 || result.Reset != 50*time.Second {
+		t.Errorf("request after 30 seconds: %+v", result)
+	}
+
+	result = b.take(limit, start.Add(time.Hour))
+	if !result.Allowed || result.Remaining != 2 {
+		t.Errorf("request after an hour: %+v, want a full bucket", result)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestSQLStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	if _, err := database.MigrateUp(database.SQLite, path); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", database.SQLiteDSN(path, database.DefaultSQLitePragmas, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	database.SQLiteWriterPool.Apply(db)
+
+	testStore(t, NewSQLStore(db, database.SQLite))
+}
+
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	// Concurrent requests must not spend the same token.
+	var wg sync.WaitGroup
+	allowed := make(chan bool, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := store.Take(ctx, "a", limit, now)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			allowed <- result.Allowed
+		}()
+	}
+	wg.Wait()
+	close(allowed)
+
+	count := 0
+	for ok := range allowed {
+		if ok {
+			count++
+		}
+	}
+	if count != limit.Requests {
+		t.Errorf("%d of 10 concurrent requests allowed, want %d", count, limit.Requests)
+	}
+
+	if result, err := store.Take(ctx, "b", limit, now); err != nil || !result.Allowed {
+		t.Errorf("other key: result = %+v, err = %v", result, err)
+	}
+
+	if err := store.Prune(ctx, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	result, err := store.Take(ctx, "a", limit, now.Add(time.Minute))
+	if err != nil || !result.Allowed || result.Remaining != limit.Requests-1 {
+		t.Errorf("after pruning: result = %+v, err = %v, want a full bucket", result, err)
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"rest-api-go-gin/internal/database"
+	"time"
+)
+
+// SQLStore keeps the buckets in the rate_limits table, so that every instance
+// of the API sharing the database shares the limits. It works with SQLite and
+// PostgreSQL, but every Take writes to the database: on SQLite each request
+// would wait for the single writer, so the API only uses it on PostgreSQL.
+type SQLStore struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+// NewSQLStore returns a store in the database db, which must be a connection
+// pool that can write.
+func NewSQLStore(db *sql.DB, dialect database.Dialect) *SQLStore {
+	return &SQLStore{db: db, dialect: dialect}
+}
+
+func (s *SQLStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback()
+
+	// Create the bucket full if it does not exist yet, so that the row can be
+	// locked below.
+	fresh := newBucket(limit, now)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rate_limits (bucket, tokens, updated_at, full_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (bucket) DO NOTHING
+	`, key, fresh.tokens, now.UnixMicro(), now.UnixMicro())
+	if err != nil {
+		return Result{}, err
+	}
+
+	query := `SELECT tokens, updated_at FROM rate_limits WHERE bucket = $1`
+	if s.dialect == database.Postgres {
+		query += ` FOR UPDATE`
+	}
+
+	var b bucket
+	var updated int64
+	if err := tx.QueryRowContext(ctx, query, key).Scan(&b.tokens, &updated); err != nil {
+		return Result{}, err
+	}
+	b.updated = time.UnixMicro(updated)
+
+	result := b.take(limit, now)
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE rate_limits SET tokens = $1, updated_at = $2, full_at = $3 WHERE bucket = $4
+	`, b.tokens, b.updated.UnixMicro(), b.fullAt.UnixMicro(), key)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result, tx.Commit()
+}
+
+func (s *SQLStore) Prune(ctx context.Context, now time.Time) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM rate_limits WHERE full_at <= $1`, now.UnixMicro())
+	return err
+}
@@ -0,0 +1,252 @@
+// Package seed fills a database with users, events and attendees, either
+// generated at random or loaded from fixture files. It works through the
+// repositories, so tests can seed the in-memory models the same way.
+package seed
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"rest-api-go-gin/internal/database"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed fixtures/*.yaml
+var fixtures embed.FS
+
+// Fixture is a set of rows to create. Events refer to their owner and
+// attendees by email and to their category by slug.
+type Fixture struct {
+	Users  []User  `json:"users" yaml:"users"`
+	Events []Event `json:"events" yaml:"events"`
+}
+
+type User struct {
+	Email    string `json:"email" yaml:"email"`
+	Name     string `json:"name" yaml:"name"`
+	Password string `json:"password" yaml:"password"`
+	Admin    bool   `json:"admin,omitempty" yaml:"admin,omitempty"`
+}
+
+// Event is created as a draft and then moved to Status the way the API would
+// move it, so that its revisions look real. A scheduled event is published
+// on its date.
+type Event struct {
+	Owner        string   `json:"owner" yaml:"owner"`
+	Name         string   `json:"name" yaml:"name"`
+	Description  string   `json:"description" yaml:"description"`
+	Date         string   `json:"date" yaml:"date"`
+	Location     string   `json:"location" yaml:"location"`
+	Category     string   `json:"category,omitempty" yaml:"category,omitempty"`
+	Tags         []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Status       string   `json:"status,omitempty" yaml:"status,omitempty"`
+	CancelReason string   `json:"cancelReason,omitempty" yaml:"cancelReason,omitempty"`
+	Attendees    []string `json:"attendees,omitempty" yaml:"attendees,omitempty"`
+}
+
+// Summary counts the rows a fixture created.
+type Summary struct {
+	Users     int
+	Events    int
+	Attendees int
+}
+
+// FixtureNames returns the names of the fixture sets built into the binary.
+func FixtureNames() []string {
+	names := []string{}
+
+	entries, _ := fs.ReadDir(fixtures, "fixtures")
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".yaml"))
+	}
+
+	return names
+}
+
+// Load reads a fixture set. name is either one of FixtureNames or the path
+// of a .yaml, .yml or .json file.
+func Load(name string) (*Fixture, error) {
+	ext := filepath.Ext(name)
+
+	var data []byte
+	var err error
+
+	if ext == "" {
+		data, err = fixtures.ReadFile("fixtures/" + name + ".yaml")
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("unknown fixture set %q, available: %s", name, strings.Join(FixtureNames(), ", "))
+		}
+		ext = ".yaml"
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data, ext)
+}
+
+// Parse decodes a fixture set from YAML or JSON, chosen by a file extension
+// such as ".json". Unknown fields are an error, to catch typos.
+func Parse(data []byte, ext string) (*Fixture, error) {
+	var fixture Fixture
+
+	switch strings.ToLower(ext) {
+	case ".json":
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&fixture); err != nil {
+			return nil, fmt.Errorf("parse fixture: %w", err)
+		}
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&fixture); err != nil {
+			return nil, fmt.Errorf("parse fixture: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported fixture format %q", ext)
+	}
+
+	return &fixture, nil
+}
+
+// Apply creates the users of the fixture, then its events and their
+// attendees. Users whose email is taken are left as they are, and events may
+// refer to users created earlier, so fixtures can build on each other.
+func (f *Fixture) Apply(ctx context.Context, models database.Models) (*Summary, error) {
+	summary := &Summary{}
+
+	categories, err := models.Categories.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categoryIDs := map[string]int{}
+	for _, category := range categories {
+		categoryIDs[category.Slug] = category.ID
+	}
+
+	users := map[string]*database.User{}
+	hashes := map[string][]byte{}
+
+	for _, u := range f.Users {
+		hash, ok := hashes[u.Password]
+		if !ok {
+			hash, err = bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+			if err != nil {
+				return summary, fmt.Errorf("user %s: %w", u.Email, err)
+			}
+			hashes[u.Password] = hash
+		}
+
+		user := &database.User{Email: u.Email, Name: u.Name, Password: string(hash), IsAdmin: u.Admin}
+		err := models.Users.Insert(ctx, user)
+		if errors.Is(err, database.ErrConflict) {
+			// Seeded before; keep the existing user.
+			continue
+		}
+		if err != nil {
+			return summary, fmt.Errorf("user %s: %w", u.Email, err)
+		}
+
+		users[u.Email] = user
+		summary.Users++
+	}
+
+	user := func(email string) (*database.User, error) {
+		if user, ok := users[email]; ok {
+			return user, nil
+		}
+
+		user, err := models.Users.GetByEmail(ctx, email)
+		if err != nil {
+			return nil, fmt.Errorf("user %s: %w", email, err)
+		}
+
+		users[email] = user
+		return user, nil
+	}
+
+	for _, e := range f.Events {
+		owner, err := user(e.Owner)
+		if err != nil {
+			return summary, fmt.Errorf("event %q: %w", e.Name, err)
+		}
+
+		event := &database.Event{
+			OwnerID:     owner.ID,
+			Name:        e.Name,
+			Description: e.Description,
+			Date:        e.Date,
+			Location:    e.Location,
+			Tags:        e.Tags,
+		}
+
+		if e.Category != "" {
+			id, ok := categoryIDs[e.Category]
+			if !ok {
+				return summary, fmt.Errorf("event %q: unknown category %q", e.Name, e.Category)
+			}
+			event.CategoryID = &id
+		}
+
+		if err := models.Events.Insert(ctx, event); err != nil {
+			return summary, fmt.Errorf("event %q: %w", e.Name, err)
+		}
+		summary.Events++
+
+		for _, change := range statusChanges(e) {
+			if _, err := models.Events.Transition(ctx, event.ID, owner.ID, change); err != nil {
+				return summary, fmt.Errorf("event %q: %w", e.Name, err)
+			}
+		}
+
+		for _, email := range e.Attendees {
+			attendee, err := user(email)
+			if err != nil {
+				return summary, fmt.Errorf("event %q: %w", e.Name, err)
+			}
+
+			_, err = models.Attendees.Insert(ctx, &database.Attendee{EventID: event.ID, UserID: attendee.ID}, owner.ID)
+			if err != nil {
+				return summary, fmt.Errorf("event %q: attendee %s: %w", e.Name, email, err)
+			}
+			summary.Attendees++
+		}
+	}
+
+	return summary, nil
+}
+
+// statusChanges returns the transitions that take a new draft to the status
+// of the fixture event.
+func statusChanges(e Event) []database.StatusChange {
+	publish := database.StatusChange{Status: database.EventStatusPublished}
+
+	switch e.Status {
+	case "", database.EventStatusDraft:
+		return nil
+	case database.EventStatusScheduled:
+		change := database.StatusChange{Status: database.EventStatusScheduled}
+		if date, err := time.Parse(time.DateOnly, e.Date); err == nil {
+			change.PublishAt = &date
+		}
+		return []database.StatusChange{change}
+	case database.EventStatusCompleted:
+		return []database.StatusChange{publish, {Status: database.EventStatusCompleted}}
+	case database.EventStatusCancelled:
+		return []database.StatusChange{publish, {Status: database.EventStatusCancelled, CancelReason: e.CancelReason}}
+	default:
+		return []database.StatusChange{{Status: e.Status}}
+	}
+}
@@ -0,0 +1,126 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"rest-api-go-gin/internal/database"
+	"strings"
+	"time"
+)
+
+// DefaultPassword is the password of every generated user.
+const DefaultPassword = "password123"
+
+// Options sizes a generated fixture. Events are dated within the 180 days
+// after Start. The same options always generate the same fixture.
+type Options struct {
+	Users        int
+	Events       int
+	MaxAttendees int
+	Seed         uint64
+	Start        time.Time
+}
+
+var (
+	firstNames = []string{
+		"Alice", "Bobur", "Carlos", "Dilnoza", "Emma", "Farrukh", "Grace", "Hiroshi", "Irina", "Jamshid",
+		"Katrin", "Liam", "Malika", "Nikolai", "Olivia", "Pedro", "Rustam", "Sofia", "Timur", "Yulia",
+	}
+	lastNames = []string{
+		"Akhmedova", "Becker", "Chen", "Davletov", "Evans", "Fischer", "Garcia", "Hoffmann", "Ivanova",
+		"Karimov", "Lopez", "Muller", "Nazarov", "Petrova", "Rahimov", "Schmidt", "Tanaka", "Usmonov",
+	}
+	topics = []struct {
+		category string
+		names    []string
+		tags     []string
+	}{
+		{"technology", []string{"Go Meetup", "Cloud Native Day", "Frontend Night", "Data Engineering Workshop", "Security Hackathon"},
+			[]string{"go", "kubernetes", "javascript", "data", "security", "ai"}},
+		{"business", []string{"Startup Pitch Night", "Founders Breakfast", "Product Management Forum", "Marketing Masterclass"},
+			[]string{"startups", "networking", "product", "marketing"}},
+		{"music", []string{"Jazz Evening", "Open Mic", "Classical Quartet", "Indie Rock Live"},
+			[]string{"jazz", "live", "classical", "rock"}},
+		{"sports", []string{"City Half Marathon", "Sunday Football", "Yoga in the Park", "Chess Tournament"},
+			[]string{"running", "football", "yoga", "chess", "outdoors"}},
+		{"arts", []string{"Photography Walk", "Gallery Opening", "Poetry Reading", "Pottery Class"},
+			[]string{"photography", "painting", "poetry", "crafts"}},
+		{"community", []string{"Neighbourhood Clean-up", "Book Club", "Language Exchange", "Board Game Night"},
+			[]string{"volunteering", "books", "languages", "games", "family"}},
+	}
+	cities   = []string{"Tashkent", "Samarkand", "Bukhara", "Berlin", "Munich", "Almaty", "Istanbul", "Lisbon"}
+	places   = []string{"Central Library", "Tech Hub", "City Park", "Riverside Hall", "Old Town Square", "Community Centre"}
+	statuses = []string{
+		database.EventStatusPublished, database.EventStatusPublished, database.EventStatusPublished,
+		database.EventStatusPublished, database.EventStatusDraft, database.EventStatusScheduled,
+		database.EventStatusCancelled, database.EventStatusCompleted,
+	}
+)
+
+// Generate returns a fixture of fake but plausible users, events and
+// attendees.
+func Generate(opts Options) *Fixture {
+	r := rand.New(rand.NewPCG(opts.Seed, opts.Seed))
+	fixture := &Fixture{Users: []User{}, Events: []Event{}}
+
+	for i := range opts.Users {
+		first := firstNames[r.IntN(len(firstNames))]
+		last := lastNames[r.IntN(len(lastNames))]
+
+		fixture.Users = append(fixture.Users, User{
+			Email:    fmt.Sprintf("%s.%s%d@example.com", strings.ToLower(first), strings.ToLower(last), i+1),
+			Name:     first + " " + last,
+			Password: DefaultPassword,
+		})
+	}
+
+	if len(fixture.Users) == 0 {
+		return fixture
+	}
+
+	start := opts.Start.UTC().Truncate(24 * time.Hour)
+
+	for range opts.Events {
+		topic := topics[r.IntN(len(topics))]
+		name := topic.names[r.IntN(len(topic.names))]
+		city := cities[r.IntN(len(cities))]
+		owner := r.IntN(len(fixture.Users))
+
+		event := Event{
+			Owner:       fixture.Users[owner].Email,
+			Name:        name + " " + city,
+			Description: fmt.Sprintf("%s in %s. Everyone is welcome, no experience needed.", name, city),
+			Date:        start.AddDate(0, 0, 1+r.IntN(180)).Format(time.DateOnly),
+			Location:    places[r.IntN(len(places))] + ", " + city,
+			Category:    topic.category,
+			Tags:        pick(r, topic.tags, 1+r.IntN(3)),
+			Status:      statuses[r.IntN(len(statuses))],
+		}
+
+		if event.Status == database.EventStatusCancelled {
+			event.CancelReason = "The venue is no longer available."
+		}
+
+		if opts.MaxAttendees > 0 && event.Status != database.EventStatusDraft {
+			for _, i := range r.Perm(len(fixture.Users))[:min(r.IntN(opts.MaxAttendees+1), len(fixture.Users))] {
+				if i != owner {
+					event.Attendees = append(event.Attendees, fixture.Users[i].Email)
+				}
+			}
+		}
+
+		fixture.Events = append(fixture.Events, event)
+	}
+
+	return fixture
+}
+
+// pick returns n different items of items in random order.
+func pick(r *rand.Rand, items []string, n int) []string {
+	picked := []string{}
+	for _, i := range r.Perm(len(items))[:min(n, len(items))] {
+		picked = append(picked, items[i])
+	}
+
+	return picked
+}
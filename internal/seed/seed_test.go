@@ -0,0 +1,77 @@
+package seed
+
+import (
+	"context"
+	"reflect"
+	"rest-api-go-gin/internal/database"
+	"testing"
+	"time"
+)
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	opts := Options{Users: 5, Events: 10, MaxAttendees: 3, Seed: 42, Start: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	first, second := Generate(opts), Generate(opts)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatal("the same options generated different fixtures")
+	}
+
+	opts.Seed++
+	if reflect.DeepEqual(first, Generate(opts)) {
+		t.Fatal("different seeds generated the same fixture")
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	models := database.NewMemoryModels()
+
+	fixture, err := Load("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := fixture.Apply(ctx, models)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *summary != (Summary{Users: 3, Events: 4, Attendees: 3}) {
+		t.Fatalf("summary = %+v", *summary)
+	}
+
+	published, err := models.Events.GetAll(ctx, database.EventFilter{Tags: []string{"go"}})
+	if err != nil || len(published) != 1 {
+		t.Fatalf("GetAll = %v, %v", published, err)
+	}
+
+	attendees, err := models.Attendees.GetAttendeesByEvent(ctx, published[0].ID)
+	if err != nil || len(attendees) != 2 {
+		t.Fatalf("GetAttendeesByEvent = %v, %v", attendees, err)
+	}
+
+	// A later fixture can refer to the users of an earlier one.
+	more, err := Parse([]byte(`{
+		"events": [{
+			"owner": "bob@example.com", "name": "Open Mic", "description": "Bring your instrument.",
+			"date": "2030-06-01", "location": "Riverside Hall", "status": "completed",
+			"attendees": ["alice@example.com"]
+		}]
+	}`), ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err = more.Apply(ctx, models)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *summary != (Summary{Events: 1, Attendees: 1}) {
+		t.Fatalf("summary = %+v", *summary)
+	}
+}
+
+func TestParseRejectsUnknownFields(t *testing.T) {
+	if _, err := Parse([]byte("users:\n  - email: a@example.com\n    nmae: A\n"), ".yaml"); err == nil {
+		t.Fatal("Parse accepted an unknown field")
+	}
+}
@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"strings"
+
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`([^\w$.])\d+(?:\.\d+)?\b`)
+)
+
+// OpenDB opens a database whose statements are recorded as spans. The spans
+// carry the sanitized statement but never the bound arguments.
+func OpenDB(driverName, dataSourceName string) (*sql.DB, error) {
+	system := semconv.DBSystemSqlite
+	if driverName == "pgx" {
+		system = semconv.DBSystemPostgreSQL
+	}
+
+	return otelsql.Open(driverName, dataSourceName,
+		otelsql.WithAttributes(system),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			DisableQuery:         true,
+			OmitConnResetSession: true,
+			OmitRows:             true,
+		}),
+		otelsql.WithAttributesGetter(statementAttributes),
+	)
+}
+
+func statementAttributes(ctx context.Context, method otelsql.Method, query string, args []driver.NamedValue) []attribute.KeyValue {
+	if query == "" {
+		return nil
+	}
+
+	return []attribute.KeyValue{semconv.DBQueryText(SanitizeStatement(query))}
+}
+
+// SanitizeStatement collapses the whitespace of a SQL statement and replaces
+// string and numeric literals with a question mark, so that no values end up
+// in the trace.
+func SanitizeStatement(query string) string {
+	query = stringLiteral.ReplaceAllString(query, "?")
+	query = numericLiteral.ReplaceAllString(query, "${1}?")
+
+	return strings.Join(strings.Fields(query), " ")
+}
@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterNone   = "none"
+)
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. Spans are sent to the given exporter: otlp, stdout or none. The
+// OTLP exporter is configured through the standard OTEL_EXPORTER_OTLP_*
+// variables. The returned function flushes pending spans and must be called
+// before the process exits.
+func Setup(ctx context.Context, exporter, serviceName, serviceVersion string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+
+	switch strings.ToLower(exporter) {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("invalid trace exporter %q, expected otlp, stdout or none", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s trace exporter: %w", exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(serviceVersion),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
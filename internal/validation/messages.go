@@ -0,0 +1,170 @@
+package validation
+
+import (
+	"github.com/go-playground/locales"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-playground/validator/v10/non-standard/validators"
+)
+
+// catalog holds the messages of one language. Texts are keyed by validation
+// tag; min and max have one text per thing measured, and the type texts are
+// for JSON values of the wrong type. Units are the counted
+// forms used by min and max, one per plural rule of the language.
+type catalog struct {
+	texts map[string]string
+	units map[string]map[locales.PluralRule]string
+}
+
+var catalogs = map[string]catalog{
+	"en": {
+		texts: map[string]string{
+			"invalid":          "is invalid",
+			"required":         "is required",
+			"required_with":    "is required when {0} is set",
+			"required_without": "is required when {0} is not set",
+			"email":            "must be a valid email address",
+			"datetime":         "must be a date in the format {0}",
+			"min-characters":   "must be at least {0} long",
+			"max-characters":   "must be at most {0} long",
+			"min-items":        "must contain at least {0}",
+			"max-items":        "must contain at most {0}",
+			"min-number":       "must be at least {0}",
+			"max-number":       "must be at most {0}",
+			"type-string":      "must be a string",
+			"type-boolean":     "must be a boolean",
+			"type-integer":     "must be an integer",
+			"type-number":      "must be a number",
+			"type-array":       "must be an array",
+			"type-object":      "must be an object",
+		},
+		units: map[string]map[locales.PluralRule]string{
+			"characters": {locales.PluralRuleOne: "{0} character", locales.PluralRuleOther: "{0} characters"},
+			"items":      {locales.PluralRuleOne: "{0} item", locales.PluralRuleOther: "{0} items"},
+		},
+	},
+	"ru": {
+		texts: map[string]string{
+			"invalid":          "имеет недопустимое значение",
+			"required":         "обязательно для заполнения",
+			"required_with":    "обязательно, если задано поле {0}",
+			"required_without": "обязательно, если не задано поле {0}",
+			"email":            "должно быть корректным адресом электронной почты",
+			"datetime":         "должно быть датой в формате {0}",
+			"min-characters":   "должно содержать не менее {0}",
+			"max-characters":   "должно содержать не более {0}",
+			"min-items":        "должно содержать не менее {0}",
+			"max-items":        "должно содержать не более {0}",
+			"min-number":       "должно быть не меньше {0}",
+			"max-number":       "должно быть не больше {0}",
+			"type-string":      "должно быть строкой",
+			"type-boolean":     "должно быть логическим значением",
+			"type-integer":     "должно быть целым числом",
+			"type-number":      "должно быть числом",
+			"type-array":       "должно быть массивом",
+			"type-object":      "должно быть объектом",
+		},
+		units: map[string]map[locales.PluralRule]string{
+			"characters": {
+				locales.PluralRuleOne:   "{0} символа",
+				locales.PluralRuleFew:   "{0} символов",
+				locales.PluralRuleMany:  "{0} символов",
+				locales.PluralRuleOther: "{0} символа",
+			},
+			"items": {
+				locales.PluralRuleOne:   "{0} элемента",
+				locales.PluralRuleFew:   "{0} элементов",
+				locales.PluralRuleMany:  "{0} элементов",
+				locales.PluralRuleOther: "{0} элемента",
+			},
+		},
+	},
+	"uz": {
+		texts: map[string]string{
+			"invalid":          "noto‘g‘ri qiymatga ega",
+			"required":         "to‘ldirilishi shart",
+			"required_with":    "{0} ko‘rsatilganda to‘ldirilishi shart",
+			"required_without": "{0} ko‘rsatilmaganda to‘ldirilishi shart",
+			"email":            "haqiqiy elektron pochta manzili bo‘lishi kerak",
+			"datetime":         "{0} formatidagi sana bo‘lishi kerak",
+			"min-characters":   "kamida {0}dan iborat bo‘lishi kerak",
+			"max-characters":   "ko‘pi bilan {0}dan iborat bo‘lishi kerak",
+			"min-items":        "kamida {0}dan iborat bo‘lishi kerak",
+			"max-items":        "ko‘pi bilan {0}dan iborat bo‘lishi kerak",
+			"min-number":       "kamida {0} bo‘lishi kerak",
+			"max-number":       "ko‘pi bilan {0} bo‘lishi kerak",
+			"type-string":      "satr bo‘lishi kerak",
+			"type-boolean":     "mantiqiy qiymat bo‘lishi kerak",
+			"type-integer":     "butun son bo‘lishi kerak",
+			"type-number":      "son bo‘lishi kerak",
+			"type-array":       "massiv bo‘lishi kerak",
+			"type-object":      "obyekt bo‘lishi kerak",
+		},
+		units: map[string]map[locales.PluralRule]string{
+			"characters": {locales.PluralRuleOne: "{0} ta belgi", locales.PluralRuleOther: "{0} ta belgi"},
+			"items":      {locales.PluralRuleOne: "{0} ta element", locales.PluralRuleOther: "{0} ta element"},
+		},
+	},
+	"de": {
+		texts: map[string]string{
+			"invalid":          "ist ungültig",
+			"required":         "ist erforderlich",
+			"required_with":    "ist erforderlich, wenn {0} gesetzt ist",
+			"required_without": "ist erforderlich, wenn {0} nicht gesetzt ist",
+			"email":            "muss eine gültige E-Mail-Adresse sein",
+			"datetime":         "muss ein Datum im Format {0} sein",
+			"min-characters":   "muss mindestens {0} lang sein",
+			"max-characters":   "darf höchstens {0} lang sein",
+			"min-items":        "muss mindestens {0} enthalten",
+			"max-items":        "darf höchstens {0} enthalten",
+			"min-number":       "muss mindestens {0} sein",
+			"max-number":       "darf höchstens {0} sein",
+			"type-string":      "muss eine Zeichenkette sein",
+			"type-boolean":     "muss ein Wahrheitswert sein",
+			"type-integer":     "muss eine ganze Zahl sein",
+			"type-number":      "muss eine Zahl sein",
+			"type-array":       "muss ein Array sein",
+			"type-object":      "muss ein Objekt sein",
+		},
+		units: map[string]map[locales.PluralRule]string{
+			"characters": {locales.PluralRuleOne: "{0} Zeichen", locales.PluralRuleOther: "{0} Zeichen"},
+			"items":      {locales.PluralRuleOne: "{0} Eintrag", locales.PluralRuleOther: "{0} Einträge"},
+		},
+	},
+}
+
+// customValidators are the validators this project adds to the standard ones.
+var customValidators = []struct {
+	tag      string
+	fn       validator.Func
+	messages map[string]string
+}{
+	{
+		tag: "notblank",
+		fn:  validators.NotBlank,
+		messages: map[string]string{
+			"en": "must not be blank",
+			"ru": "не может быть пустым",
+			"uz": "bo‘sh bo‘lishi mumkin emas",
+			"de": "darf nicht leer sein",
+		},
+	},
+}
+
+func addCatalog(trans ut.Translator, c catalog) error {
+	for key, text := range c.texts {
+		if err := trans.Add(key, text, false); err != nil {
+			return err
+		}
+	}
+
+	for key, forms := range c.units {
+		for rule, text := range forms {
+			if err := trans.AddCardinal(key, text, rule, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,263 @@
+// Package validation turns request validation errors into messages clients
+// can show to people, in the language they asked for.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ru"
+	"github.com/go-playground/locales/uz"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/language"
+)
+
+// Translations holds the validation messages of every supported language.
+// English is the fallback for clients that accept none of them.
+type Translations struct {
+	universal *ut.UniversalTranslator
+	matcher   language.Matcher
+	locales   []string
+}
+
+// Setup registers the translated messages and the project's own validators
+// with v. It also makes v report fields by their JSON names, the names
+// clients send, rather than by Go struct field names.
+func Setup(v *validator.Validate) (*Translations, error) {
+	english := en.New()
+	translators := []locales.Translator{english, ru.New(), uz.New(), de.New()}
+
+	t := &Translations{
+		universal: ut.New(english, translators...),
+	}
+
+	tags := make([]language.Tag, 0, len(translators))
+	for _, translator := range translators {
+		tags = append(tags, language.MustParse(translator.Locale()))
+		t.locales = append(t.locales, translator.Locale())
+
+		trans, _ := t.universal.GetTranslator(translator.Locale())
+		if err := addCatalog(trans, catalogs[translator.Locale()]); err != nil {
+			return nil, fmt.Errorf("validation messages for %s: %w", translator.Locale(), err)
+		}
+	}
+	t.matcher = language.NewMatcher(tags)
+
+	v.RegisterTagNameFunc(jsonName)
+
+	for _, custom := range customValidators {
+		if err := t.RegisterValidation(v, custom.tag, custom.fn, custom.messages); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// RegisterValidation adds a validator for tag together with its message in
+// each language, keyed by locale such as "en". Languages without a message
+// fall back to English.
+func (t *Translations) RegisterValidation(v *validator.Validate, tag string, fn validator.Func, messages map[string]string) error {
+	if _, ok := messages["en"]; !ok {
+		return fmt.Errorf("validator %s: an English message is required", tag)
+	}
+
+	if err := v.RegisterValidation(tag, fn); err != nil {
+		return fmt.Errorf("validator %s: %w", tag, err)
+	}
+
+	for locale, message := range messages {
+		trans, found := t.universal.GetTranslator(locale)
+		if !found {
+			return fmt.Errorf("validator %s: unsupported language %s", tag, locale)
+		}
+		if err := trans.Add(tag, message, true); err != nil {
+			return fmt.Errorf("validator %s: %w", tag, err)
+		}
+	}
+
+	return nil
+}
+
+// Translator returns the translator for the language the client prefers
+// according to an Accept-Language header value.
+func (t *Translations) Translator(acceptLanguage string) ut.Translator {
+	_, index := language.MatchStrings(t.matcher, acceptLanguage)
+
+	trans, _ := t.universal.GetTranslator(t.locales[index])
+	return trans
+}
+
+// jsonName is the tag name function Setup registers: the name of the field in
+// its json tag, or its Go name if the tag has none.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// Message explains a failed validation rule in the translator's language. It
+// does not repeat the field name, which clients get alongside. value is the
+// struct that was validated; rules that refer to other fields name them by
+// their JSON names.
+func (t *Translations) Message(trans ut.Translator, fe validator.FieldError, value any) string {
+	if message, err := message(trans, fe, value); err == nil {
+		return message
+	}
+
+	fallback := t.universal.GetFallback()
+	if message, err := message(fallback, fe, value); err == nil {
+		return message
+	}
+
+	message, _ := fallback.T("invalid")
+	return message
+}
+
+// TypeMessage explains in the translator's language that a JSON value does
+// not fit the Go type typ it was decoded into, as reported by
+// json.UnmarshalTypeError.
+func (t *Translations) TypeMessage(trans ut.Translator, typ reflect.Type) string {
+	key := "type-" + jsonType(typ)
+
+	if message, err := trans.T(key); err == nil {
+		return message
+	}
+
+	message, _ := t.universal.GetFallback().T(key)
+	return message
+}
+
+// jsonType names the kind of JSON value that decodes into t.
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func message(trans ut.Translator, fe validator.FieldError, value any) (string, error) {
+	switch fe.Tag() {
+	case "required_with", "required_without":
+		fields := strings.Fields(fe.Param())
+		for i, field := range fields {
+			fields[i] = jsonFieldName(value, fe, field)
+		}
+		return trans.T(fe.Tag(), strings.Join(fields, ", "))
+	case "datetime":
+		return trans.T(fe.Tag(), dateLayout.Replace(fe.Param()))
+	case "min", "max":
+		return sizeMessage(trans, fe)
+	default:
+		return trans.T(fe.Tag())
+	}
+}
+
+// sizeMessage words a min or max rule by what is measured: the length of a
+// string, the number of items in a list or the value of a number.
+func sizeMessage(trans ut.Translator, fe validator.FieldError) (string, error) {
+	var unit string
+
+	switch fe.Kind() {
+	case reflect.String:
+		unit = "characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = "items"
+	default:
+		return trans.T(fe.Tag()+"-number", fe.Param())
+	}
+
+	n, err := strconv.ParseFloat(fe.Param(), 64)
+	if err != nil {
+		return "", err
+	}
+
+	size, err := trans.C(unit, n, 0, trans.FmtNumber(n, 0))
+	if err != nil {
+		return "", err
+	}
+
+	return trans.T(fe.Tag()+"-"+unit, size)
+}
+
+// dateLayout turns a Go time layout into the notation clients know.
+var dateLayout = strings.NewReplacer("2006", "YYYY", "01", "MM", "02", "DD", "15", "hh", "04", "mm", "05", "ss")
+
+// jsonFieldName returns the JSON name of the Go field named by a validation
+// rule parameter. The field belongs to the struct holding the field fe reports
+// on, which is found by following fe's struct namespace, such as
+// "Event.Venue.Latitude", from the type of value. If the field cannot be
+// found, its Go name is returned.
+func jsonFieldName(value any, fe validator.FieldError, field string) string {
+	parent := reflect.TypeOf(value)
+	if parent == nil {
+		return field
+	}
+
+	// The first segment names the validated struct and the last the field.
+	segments := strings.Split(fe.StructNamespace(), ".")
+	for _, segment := range segments[1 : len(segments)-1] {
+		parent = elem(parent)
+		if parent.Kind() != reflect.Struct {
+			return field
+		}
+
+		name, _, _ := strings.Cut(segment, "[")
+		f, ok := parent.FieldByName(name)
+		if !ok {
+			return field
+		}
+		parent = f.Type
+	}
+
+	parent = elem(parent)
+	if parent.Kind() != reflect.Struct {
+		return field
+	}
+
+	if f, ok := parent.FieldByName(field); ok {
+		if name := jsonName(f); name != "" {
+			return name
+		}
+	}
+
+	return field
+}
+
+// elem returns the type a pointer points to or a collection holds, repeatedly.
+func elem(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type place struct {
+	URL       string   `json:"url" validate:"required_with=Latitude"`
+	Latitude  *float64 `json:"lat"`
+	Longitude *float64 `json:"lng" validate:"required_with=Latitude"`
+}
+
+type event struct {
+	Name  string `json:"name" validate:"notblank"`
+	Place place  `json:"place"`
+}
+
+func setup(t *testing.T) (*validator.Validate, *Translations) {
+	t.Helper()
+
+	v := validator.New()
+	translations, err := Setup(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return v, translations
+}
+
+func TestTranslator(t *testing.T) {
+	_, translations := setup(t)
+
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"", "en"},
+		{"en-GB", "en"},
+		{"ru-RU,ru;q=0.9,en;q=0.8", "ru"},
+		{"uz-Latn-UZ", "uz"},
+		{"fr-FR, de;q=0.5", "de"},
+		{"fr-FR", "en"},
+		{"not a language", "en"},
+	}
+
+	for _, tt := range tests {
+		if got := translations.Translator(tt.acceptLanguage).Locale(); got != tt.want {
+			t.Errorf("Translator(%q) = %s, want %s", tt.acceptLanguage, got, tt.want)
+		}
+	}
+}
+
+func TestMessages(t *testing.T) {
+	v, translations := setup(t)
+
+	latitude := 41.3
+	value := &event{Name: "  ", Place: place{Latitude: &latitude}}
+
+	var errs validator.ValidationErrors
+	if !errors.As(v.Struct(value), &errs) {
+		t.Fatalf("Struct(%+v) did not fail validation", value)
+	}
+
+	tests := []struct {
+		locale string
+		field  string
+		want   string
+	}{
+		{"en", "name", "must not be blank"},
+		{"ru", "name", "не может быть пустым"},
+		{"uz", "name", "bo‘sh bo‘lishi mumkin emas"},
+		{"de", "name", "darf nicht leer sein"},
+		{"en", "url", "is required when lat is set"},
+		{"en", "lng", "is required when lat is set"},
+		{"de", "url", "ist erforderlich, wenn lat gesetzt ist"},
+	}
+
+	for _, tt := range tests {
+		trans := translations.Translator(tt.locale)
+
+		found := false
+		for _, fe := range errs {
+			if fe.Field() != tt.field {
+				continue
+			}
+			found = true
+
+			if got := translations.Message(trans, fe, value); got != tt.want {
+				t.Errorf("%s message of %s = %q, want %q", tt.locale, tt.field, got, tt.want)
+			}
+		}
+		if !found {
+			t.Errorf("no error reported for field %s in %v", tt.field, errs)
+		}
+	}
+}
+
+func TestTypeMessages(t *testing.T) {
+	_, translations := setup(t)
+
+	var latitude *float64
+
+	tests := []struct {
+		locale string
+		typ    reflect.Type
+		want   string
+	}{
+		{"en", reflect.TypeOf(""), "must be a string"},
+		{"en", reflect.TypeOf(latitude), "must be a number"},
+		{"ru", reflect.TypeOf(0), "должно быть целым числом"},
+		{"uz", reflect.TypeOf(true), "mantiqiy qiymat bo‘lishi kerak"},
+		{"de", reflect.TypeOf([]string{}), "muss ein Array sein"},
+		{"de", reflect.TypeOf(place{}), "muss ein Objekt sein"},
+	}
+
+	for _, tt := range tests {
+		trans := translations.Translator(tt.locale)
+		if got := translations.TypeMessage(trans, tt.typ); got != tt.want {
+			t.Errorf("%s message for %s = %q, want %q", tt.locale, tt.typ, got, tt.want)
+		}
+	}
+}